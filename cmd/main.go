@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"myproj/internal/pkg/storage"
 )
@@ -10,6 +11,7 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	defer s.Close(context.Background())
 
 	s.Set("key1", "value1")
 	s.Set("key2", 133.7)
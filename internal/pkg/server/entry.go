@@ -0,0 +1,75 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// errNoValue is returned for a body without "value". A JSON null is a value of
+// its own, so it must be sent explicitly.
+var errNoValue = errors.New(`"value" is missing`)
+
+// UnmarshalJSON decodes an Entry, requiring the "value" field. Objects and
+// arrays are kept as documents, numbers as float64.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	type entry Entry
+
+	var v entry
+	if err := decodeWithValue(data, &v); err != nil {
+		return err
+	}
+
+	*e = Entry(v)
+	return nil
+}
+
+// UnmarshalJSON decodes an EntrySet, requiring the "value" field.
+func (e *EntrySet) UnmarshalJSON(data []byte) error {
+	type entry EntrySet
+
+	var v entry
+	if err := decodeWithValue(data, &v); err != nil {
+		return err
+	}
+
+	*e = EntrySet(v)
+	return nil
+}
+
+// UnmarshalJSON decodes an EntryWithTTL, requiring the "value" field.
+func (e *EntryWithTTL) UnmarshalJSON(data []byte) error {
+	type entry EntryWithTTL
+
+	var v entry
+	if err := decodeWithValue(data, &v); err != nil {
+		return err
+	}
+
+	*e = EntryWithTTL(v)
+	return nil
+}
+
+// UnmarshalJSON decodes an EntryJSONSet, requiring the "value" field.
+func (e *EntryJSONSet) UnmarshalJSON(data []byte) error {
+	type entry EntryJSONSet
+
+	var v entry
+	if err := decodeWithValue(data, &v); err != nil {
+		return err
+	}
+
+	*e = EntryJSONSet(v)
+	return nil
+}
+
+func decodeWithValue(data []byte, v any) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if _, ok := fields["value"]; !ok {
+		return errNoValue
+	}
+
+	return json.Unmarshal(data, v)
+}
@@ -2,37 +2,155 @@ package server
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
+	"myproj/internal/pkg/storage"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// storageErrorStatus picks the response code for an error returned by storage.
+func storageErrorStatus(err error) int {
+	if errors.Is(err, storage.ErrWrongType) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, storage.ErrVersionMismatch) {
+		return http.StatusPreconditionFailed
+	}
+	if errors.Is(err, storage.ErrOutOfMemory) {
+		return http.StatusInsufficientStorage
+	}
+
+	return http.StatusBadGateway
+}
+
 func (r *Server) handlerSet(ctx *gin.Context) {
 	key := ctx.Param("key")
 
-	var v Entry
+	var v EntrySet
 	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
 		ctx.AbortWithStatus(http.StatusBadGateway)
 		return
 	}
 
-	r.storage.Set(key, v.Value)
+	opts := storage.SetOptions{
+		NX:      v.NX,
+		XX:      v.XX,
+		Get:     v.Get,
+		KeepTTL: v.KeepTTL,
+		TTL:     time.Duration(v.Seconds) * time.Second,
+	}
+
+	switch {
+	case ctx.GetHeader("If-Match") != "" || ctx.GetHeader("If-None-Match") != "":
+		if opts != (storage.SetOptions{}) {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status":  "false",
+				"message": "set options cannot be combined with If-Match or If-None-Match",
+			})
+			return
+		}
+		r.setIfMatch(ctx, key, v.Value)
+	case opts == (storage.SetOptions{}):
+		if err := r.storage.Set(key, v.Value); err != nil {
+			ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+				"status":  "false",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		ctx.Status(http.StatusOK)
+	default:
+		r.setWithOptions(ctx, key, v.Value, opts)
+	}
+}
+
+// setWithOptions answers 412 when NX or XX kept the value from being stored
+// and sends the previous value back when opts.Get is set.
+func (r *Server) setWithOptions(ctx *gin.Context, key string, value any, opts storage.SetOptions) {
+	old, ok, err := r.storage.SetWithOptions(key, value, opts)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusPreconditionFailed
+	}
+
+	if !opts.Get {
+		ctx.Status(status)
+		return
+	}
+	ctx.JSON(status, Entry{Value: deref(old)})
+}
+
+// setIfMatch stores the value if the key is still at the version of the
+// If-Match tag. If-None-Match: * only creates the key.
+func (r *Server) setIfMatch(ctx *gin.Context, key string, value any) {
+	version := uint64(0)
+	if ctx.GetHeader("If-None-Match") != "*" {
+		var ok bool
+		if version, ok = parseETag(ctx.GetHeader("If-Match")); !ok {
+			ctx.AbortWithStatus(http.StatusPreconditionFailed)
+			return
+		}
+	}
+
+	version, err := r.storage.SetIfVersion(key, value, version)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.Header("ETag", formatETag(version))
 	ctx.Status(http.StatusOK)
 }
 
 func (r *Server) handlerGet(ctx *gin.Context) {
 	key := ctx.Param("key")
 
-	v := r.storage.Get(key)
+	v, version := r.storage.GetWithVersion(key)
 	if v == nil {
 		ctx.AbortWithStatus(http.StatusNotFound)
 		return
 	}
 
+	ctx.Header("ETag", formatETag(version))
 	ctx.JSON(http.StatusOK, Entry{Value: *v})
 }
 
+// formatETag turns a key version into a strong entity tag.
+func formatETag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// parseETag reads a version back from an If-Match header. Weak tags, lists
+// and "*" are not versions, a set conditioned on them cannot succeed.
+func parseETag(tag string) (uint64, bool) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version == 0 {
+		return 0, false
+	}
+	return version, true
+}
+
 func (r *Server) handlerHSET(ctx *gin.Context) {
 	key := ctx.Param("key")
 	field := ctx.Param("field")
@@ -45,8 +163,7 @@ func (r *Server) handlerHSET(ctx *gin.Context) {
 
 	err := r.storage.HSET(key, field, v.Value)
 	if err != nil {
-		fmt.Println(err)
-		ctx.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
 			"status":  false,
 			"message": err.Error(),
 		})
@@ -80,7 +197,7 @@ func (r *Server) handlerLPUSH(ctx *gin.Context) {
 
 	err := r.storage.LPUSH(key, v.Value)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
 			"status":  "false",
 			"message": err.Error(),
 		})
@@ -103,7 +220,7 @@ func (r *Server) handlerLPOP(ctx *gin.Context) {
 
 	val, err := r.storage.LPOP(key, v.Slice...)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
 			"status":  "false",
 			"message": err.Error(),
 		})
@@ -124,7 +241,7 @@ func (r *Server) handlerRPUSH(ctx *gin.Context) {
 
 	err := r.storage.RPUSH(key, v.Value)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
 			"status":  "false",
 			"message": err.Error(),
 		})
@@ -146,7 +263,7 @@ func (r *Server) handlerRADDTOSET(ctx *gin.Context) {
 
 	err := r.storage.RADDTOSET(key, v.Value)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
 			"status":  "false",
 			"message": err.Error(),
 		})
@@ -167,7 +284,7 @@ func (r *Server) handlerRPOP(ctx *gin.Context) {
 
 	val, err := r.storage.RPOP(key, v.Slice...)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
 			"status":  "false",
 			"message": err.Error(),
 		})
@@ -209,7 +326,7 @@ func (r *Server) handlerLGET(ctx *gin.Context) {
 
 	val, err := r.storage.LGET(key, v.Index)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
 			"status":  "fasle",
 			"message": err.Error(),
 		})
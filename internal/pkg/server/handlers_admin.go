@@ -0,0 +1,88 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"myproj/internal/pkg/storage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Server) handlerAOFStatus(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, r.storage.AOFStatus())
+}
+
+// handlerAOFRewrite answers 202 once the rewrite has started, it goes on in
+// the background and its outcome shows in the status.
+func (r *Server) handlerAOFRewrite(ctx *gin.Context) {
+	if err := r.storage.RewriteAOF(); err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, storage.ErrAOFClosed) || errors.Is(err, storage.ErrRewriteInProgress) {
+			status = http.StatusConflict
+		}
+
+		ctx.AbortWithStatusJSON(status, gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// handlerSnapshotLoad loads the snapshot uploaded as the "snapshot" file of
+// a multipart form, or sent as the raw body, as the query says. A snapshot
+// that cannot be read or an unknown mode is 400, an upload over the snapshot
+// limit is 413, and neither changes anything.
+func (r *Server) handlerSnapshotLoad(ctx *gin.Context) {
+	var v EntryLoad
+	if err := ctx.ShouldBindQuery(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, r.snapshotLimit)
+
+	var body io.Reader = ctx.Request.Body
+	if ctx.ContentType() == "multipart/form-data" {
+		header, err := ctx.FormFile("snapshot")
+		if err != nil {
+			ctx.AbortWithStatus(loadErrorStatus(err))
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	report, err := r.storage.LoadSnapshot(body, storage.LoadOptions{
+		Mode:   storage.LoadMode(v.Mode),
+		Match:  v.Match,
+		DryRun: v.DryRun,
+	})
+	if err != nil {
+		ctx.AbortWithStatusJSON(loadErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+// loadErrorStatus is 413 for an upload over the snapshot limit and 400 for
+// any other snapshot that cannot be loaded.
+func loadErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
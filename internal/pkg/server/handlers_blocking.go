@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"myproj/internal/pkg/storage"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type blockingPopFunc func(ctx context.Context, keys []string, timeout time.Duration) (*storage.PoppedElem, error)
+
+func (r *Server) handlerBLPOP(ctx *gin.Context) {
+	r.handleBlockingPop(ctx, r.storage.BLPop)
+}
+
+func (r *Server) handlerBRPOP(ctx *gin.Context) {
+	r.handleBlockingPop(ctx, r.storage.BRPop)
+}
+
+// handleBlockingPop long-polls until an element arrives, the timeout given in
+// seconds expires (204) or the client goes away.
+func (r *Server) handleBlockingPop(ctx *gin.Context, pop blockingPopFunc) {
+	var v EntryBlockingPop
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	timeout := time.Duration(v.Timeout * float64(time.Second))
+	val, err := pop(ctx.Request.Context(), v.Keys, timeout)
+	if errors.Is(err, context.Canceled) {
+		ctx.Abort()
+		return
+	}
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if val == nil {
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EntryPopped{Key: val.Key, Value: val.Value})
+}
+
+// handlerBLMOVE long-polls like handleBlockingPop, answering 204 on timeout.
+func (r *Server) handlerBLMOVE(ctx *gin.Context) {
+	var v EntryLMove
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	timeout := time.Duration(v.Timeout * float64(time.Second))
+	val, err := r.storage.BLMove(ctx.Request.Context(), v.Source, v.Destination, v.From, v.To, timeout)
+	if errors.Is(err, context.Canceled) {
+		ctx.Abort()
+		return
+	}
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if val == nil {
+		ctx.Status(http.StatusNoContent)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: *val})
+}
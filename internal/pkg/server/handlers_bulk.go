@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Server) handlerMGET(ctx *gin.Context) {
+	var v EntryKeys
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	vals := r.storage.MGet(v.Keys...)
+	res := make([]any, len(vals))
+	for i, val := range vals {
+		res[i] = deref(val)
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: res})
+}
+
+func (r *Server) handlerMSET(ctx *gin.Context) {
+	var v EntryValues
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	if err := r.storage.MSet(v.Values); err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// handlerMSETNX answers 412 when a key already exists, like SET with nx.
+func (r *Server) handlerMSETNX(ctx *gin.Context) {
+	var v EntryValues
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	ok, err := r.storage.MSetNX(v.Values)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if !ok {
+		ctx.AbortWithStatus(http.StatusPreconditionFailed)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (r *Server) handlerMPUSH(ctx *gin.Context) {
+	var v EntryLists
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	if err := r.storage.MPush(v.Lists); err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Server) handlerIncr(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	val, err := r.storage.Incr(key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerDecr(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	val, err := r.storage.Decr(key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerIncrBy(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryIncr
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.IncrBy(key, v.Delta)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerIncrByFloat(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryIncrFloat
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.IncrByFloat(key, v.Delta)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Server) handlerHDEL(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryFields
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	n, err := r.storage.HDEL(key, v.Fields...)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: n})
+}
+
+func (r *Server) handlerHGETALL(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	val, err := r.storage.HGETALL(key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerHKEYS(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	val, err := r.storage.HKEYS(key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerHVALS(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	val, err := r.storage.HVALS(key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerHLEN(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	val, err := r.storage.HLEN(key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerHEXISTS(ctx *gin.Context) {
+	key := ctx.Param("key")
+	field := ctx.Param("field")
+
+	val, err := r.storage.HEXISTS(key, field)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerHINCRBY(ctx *gin.Context) {
+	key := ctx.Param("key")
+	field := ctx.Param("field")
+
+	var v EntryIncr
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.HINCRBY(key, field, v.Delta)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
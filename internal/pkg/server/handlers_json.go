@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"myproj/internal/pkg/storage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jsonErrorStatus picks the response code for an error returned by a JSON command.
+func jsonErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrPathNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrSyntax), errors.Is(err, storage.ErrNotArray), errors.Is(err, storage.ErrNotFloat):
+		return http.StatusBadRequest
+	}
+
+	return storageErrorStatus(err)
+}
+
+func (r *Server) handlerJSONGET(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryJSONPath
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.JSONGet(key, v.Path)
+	if err != nil {
+		ctx.AbortWithStatusJSON(jsonErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if val == nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: *val})
+}
+
+func (r *Server) handlerJSONSET(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryJSONSet
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	if err := r.storage.JSONSet(key, v.Path, v.Value); err != nil {
+		ctx.AbortWithStatusJSON(jsonErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (r *Server) handlerJSONDEL(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryJSONPath
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.JSONDel(key, v.Path)
+	if err != nil {
+		ctx.AbortWithStatusJSON(jsonErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerJSONARRAPPEND(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryJSONAppend
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.JSONArrAppend(key, v.Path, v.Values...)
+	if err != nil {
+		ctx.AbortWithStatusJSON(jsonErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerJSONNUMINCRBY(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryJSONIncr
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.JSONNumIncrBy(key, v.Path, v.Delta)
+	if err != nil {
+		ctx.AbortWithStatusJSON(jsonErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
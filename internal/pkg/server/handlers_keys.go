@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"myproj/internal/pkg/storage"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Server) handlerSetWithTTL(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryWithTTL
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	err := r.storage.SetWithTTL(key, v.Value, time.Duration(v.Seconds)*time.Second)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (r *Server) handlerGetDel(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	val, err := r.storage.GetDel(key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if val == nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: *val})
+}
+
+func (r *Server) handlerGetEx(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryGetEx
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.GetEx(key, storage.GetExOptions{
+		TTL:     time.Duration(v.Seconds) * time.Second,
+		Persist: v.Persist,
+	})
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if val == nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: *val})
+}
+
+func (r *Server) handlerExpire(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryTTL
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	if !r.storage.Expire(key, time.Duration(v.Seconds)*time.Second) {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (r *Server) handlerExpireAt(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryExpireAt
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	if !r.storage.ExpireAt(key, time.Unix(v.Timestamp, 0)) {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// handlerTTL answers in whole seconds, keeping -1 for keys without
+// a deadline and -2 for missing keys.
+func (r *Server) handlerTTL(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	ttl := r.storage.TTL(key)
+	if ttl < 0 {
+		ctx.JSON(http.StatusOK, Entry{Value: int64(ttl)})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: int64(ttl.Round(time.Second) / time.Second)})
+}
+
+func (r *Server) handlerPersist(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	ctx.JSON(http.StatusOK, Entry{Value: r.storage.Persist(key)})
+}
+
+func (r *Server) handlerDel(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	ctx.JSON(http.StatusOK, Entry{Value: r.storage.Del(key)})
+}
+
+func (r *Server) handlerExists(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	ctx.JSON(http.StatusOK, Entry{Value: r.storage.Exists(key) == 1})
+}
+
+func (r *Server) handlerType(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	ctx.JSON(http.StatusOK, Entry{Value: r.storage.Type(key)})
+}
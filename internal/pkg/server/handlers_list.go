@@ -0,0 +1,206 @@
+package server
+
+import (
+	"encoding/json"
+	"myproj/internal/pkg/storage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Server) handlerLRANGE(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryRange
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.LRANGE(key, v.Start, v.Stop)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerLLEN(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	val, err := r.storage.LLEN(key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerLINDEX(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryLGET
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.LINDEX(key, v.Index)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if val == nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: *val})
+}
+
+func (r *Server) handlerLINSERT(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryLINSERT
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.LINSERT(key, v.Where, v.Pivot, v.Element)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerLREM(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryLREM
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.LREM(key, v.Count, v.Element)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerLTRIM(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryRange
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	if err := r.storage.LTRIM(key, v.Start, v.Stop); err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+func (r *Server) handlerLPOS(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryLPOS
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.LPOS(key, v.Element, storage.LPosOptions{
+		Rank:   v.Rank,
+		Count:  v.Count,
+		MaxLen: v.MaxLen,
+	})
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerLMOVE(ctx *gin.Context) {
+	var v EntryLMove
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.LMove(v.Source, v.Destination, v.From, v.To)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if val == nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: *val})
+}
+
+func (r *Server) handlerRPOPLPUSH(ctx *gin.Context) {
+	var v EntryLMove
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.RPOPLPUSH(v.Source, v.Destination)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if val == nil {
+		ctx.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: *val})
+}
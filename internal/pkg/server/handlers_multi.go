@@ -0,0 +1,468 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"myproj/internal/pkg/storage"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errUnknownCommand = errors.New("unknown command")
+	errWrongArgs      = errors.New("wrong number or type of arguments")
+)
+
+// multiCommand runs one command of a /multi request inside its transaction.
+type multiCommand func(tx *storage.Tx, args cmdArgs) (any, error)
+
+// multiCommands maps the upper-case command names accepted by /multi.
+var multiCommands = map[string]multiCommand{
+	"SET": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		key, val, err := args.keyValue()
+		if err != nil {
+			return nil, err
+		}
+		return "OK", tx.Set(key, val)
+	},
+	"GET": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		key, err := args.key()
+		if err != nil {
+			return nil, err
+		}
+		return deref(tx.Get(key)), nil
+	},
+	"DEL": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		keys, err := args.strings(0)
+		if err != nil {
+			return nil, err
+		}
+		return tx.Del(keys...), nil
+	},
+	"EXISTS": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		keys, err := args.strings(0)
+		if err != nil {
+			return nil, err
+		}
+		return tx.Exists(keys...), nil
+	},
+	"EXPIRE": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) != 2 {
+			return nil, errWrongArgs
+		}
+		key, err := args.string(0)
+		if err != nil {
+			return nil, err
+		}
+		seconds, err := args.int(1)
+		if err != nil {
+			return nil, err
+		}
+		return tx.Expire(key, time.Duration(seconds)*time.Second), nil
+	},
+	"INCR": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		key, err := args.key()
+		if err != nil {
+			return nil, err
+		}
+		return tx.Incr(key)
+	},
+	"DECR": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		key, err := args.key()
+		if err != nil {
+			return nil, err
+		}
+		return tx.Decr(key)
+	},
+	"INCRBY": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) != 2 {
+			return nil, errWrongArgs
+		}
+		key, err := args.string(0)
+		if err != nil {
+			return nil, err
+		}
+		delta, err := args.int(1)
+		if err != nil {
+			return nil, err
+		}
+		return tx.IncrBy(key, delta)
+	},
+	"HSET": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) != 3 {
+			return nil, errWrongArgs
+		}
+		key, field, err := args.keyField()
+		if err != nil {
+			return nil, err
+		}
+		return "OK", tx.HSET(key, field, args[2])
+	},
+	"HGET": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) != 2 {
+			return nil, errWrongArgs
+		}
+		key, field, err := args.keyField()
+		if err != nil {
+			return nil, err
+		}
+		return deref(tx.HGET(key, field)), nil
+	},
+	"HDEL": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		key, err := args.string(0)
+		if err != nil {
+			return nil, err
+		}
+		fields, err := args.strings(1)
+		if err != nil {
+			return nil, err
+		}
+		return tx.HDEL(key, fields...)
+	},
+	"HINCRBY": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) != 3 {
+			return nil, errWrongArgs
+		}
+		key, field, err := args.keyField()
+		if err != nil {
+			return nil, err
+		}
+		delta, err := args.int(2)
+		if err != nil {
+			return nil, err
+		}
+		return tx.HINCRBY(key, field, delta)
+	},
+	"LPUSH": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		return pushCommand(args, tx.LPUSH, tx.LLEN)
+	},
+	"RPUSH": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		return pushCommand(args, tx.RPUSH, tx.LLEN)
+	},
+	"LPOP": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		return popCommand(args, tx.LPOP)
+	},
+	"RPOP": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		return popCommand(args, tx.RPOP)
+	},
+	"LRANGE": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) != 3 {
+			return nil, errWrongArgs
+		}
+		key, err := args.string(0)
+		if err != nil {
+			return nil, err
+		}
+		start, err := args.int(1)
+		if err != nil {
+			return nil, err
+		}
+		stop, err := args.int(2)
+		if err != nil {
+			return nil, err
+		}
+		return tx.LRANGE(key, start, stop)
+	},
+	"LLEN": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		key, err := args.key()
+		if err != nil {
+			return nil, err
+		}
+		return tx.LLEN(key)
+	},
+	"LMOVE": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) != 4 {
+			return nil, errWrongArgs
+		}
+		names, err := args.strings(0)
+		if err != nil {
+			return nil, err
+		}
+		val, err := tx.LMove(names[0], names[1], names[2], names[3])
+		return deref(val), err
+	},
+	"SADD": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		return membersCommand(args, tx.SADD)
+	},
+	"SREM": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		return membersCommand(args, tx.SREM)
+	},
+	"SMEMBERS": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		key, err := args.key()
+		if err != nil {
+			return nil, err
+		}
+		return tx.SMEMBERS(key)
+	},
+	"ZADD": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) < 3 || len(args)%2 == 0 {
+			return nil, errWrongArgs
+		}
+		key, err := args.string(0)
+		if err != nil {
+			return nil, err
+		}
+
+		members := make([]storage.ZMember, 0, len(args)/2)
+		for i := 1; i < len(args); i += 2 {
+			score, err := args.float(i)
+			if err != nil {
+				return nil, err
+			}
+			member, err := args.string(i + 1)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, storage.ZMember{Member: member, Score: score})
+		}
+		return tx.ZADD(key, members...)
+	},
+	"ZINCRBY": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) != 3 {
+			return nil, errWrongArgs
+		}
+		key, err := args.string(0)
+		if err != nil {
+			return nil, err
+		}
+		increment, err := args.float(1)
+		if err != nil {
+			return nil, err
+		}
+		member, err := args.string(2)
+		if err != nil {
+			return nil, err
+		}
+		return tx.ZINCRBY(key, increment, member)
+	},
+	"ZREM": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		return membersCommand(args, tx.ZREM)
+	},
+	"ZSCORE": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) != 2 {
+			return nil, errWrongArgs
+		}
+		key, member, err := args.keyField()
+		if err != nil {
+			return nil, err
+		}
+		score, err := tx.ZSCORE(key, member)
+		if err != nil || score == nil {
+			return nil, err
+		}
+		return *score, nil
+	},
+	"JSON.SET": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) != 3 {
+			return nil, errWrongArgs
+		}
+		key, path, err := args.keyField()
+		if err != nil {
+			return nil, err
+		}
+		return "OK", tx.JSONSet(key, path, args[2])
+	},
+	"JSON.GET": func(tx *storage.Tx, args cmdArgs) (any, error) {
+		if len(args) != 2 {
+			return nil, errWrongArgs
+		}
+		key, path, err := args.keyField()
+		if err != nil {
+			return nil, err
+		}
+		val, err := tx.JSONGet(key, path)
+		return deref(val), err
+	},
+}
+
+// handlerMULTI runs a JSON array of commands as one transaction. Either all of
+// them are applied or, on the first failing command, none.
+func (r *Server) handlerMULTI(ctx *gin.Context) {
+	var cmds []EntryCommand
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&cmds); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	results := make([]EntryResult, 0, len(cmds))
+	failed := -1
+	err := r.storage.Update(func(tx *storage.Tx) error {
+		for i, cmd := range cmds {
+			res, err := runCommand(tx, cmd)
+			if err != nil {
+				failed = i
+				results = append(results, EntryResult{Error: err.Error()})
+				return err
+			}
+			results = append(results, EntryResult{Result: res})
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.AbortWithStatusJSON(multiErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": fmt.Sprintf("command %d: %s", failed, err.Error()),
+			"index":   failed,
+			"results": results,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+func runCommand(tx *storage.Tx, cmd EntryCommand) (any, error) {
+	run, ok := multiCommands[strings.ToUpper(cmd.Command)]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", errUnknownCommand, cmd.Command)
+	}
+
+	return run(tx, cmd.Args)
+}
+
+// multiErrorStatus picks the response code for the command that failed a /multi request.
+func multiErrorStatus(err error) int {
+	if errors.Is(err, errUnknownCommand) || errors.Is(err, errWrongArgs) {
+		return http.StatusBadRequest
+	}
+
+	return jsonErrorStatus(err)
+}
+
+func pushCommand(args cmdArgs, push func(string, []any) error, length func(string) (int, error)) (any, error) {
+	if len(args) < 2 {
+		return nil, errWrongArgs
+	}
+	key, err := args.string(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := push(key, args[1:]); err != nil {
+		return nil, err
+	}
+	return length(key)
+}
+
+func popCommand(args cmdArgs, pop func(string, ...int) ([]any, error)) (any, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, errWrongArgs
+	}
+	key, err := args.string(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 1 {
+		elems, err := pop(key)
+		if err != nil || len(elems) == 0 {
+			return nil, err
+		}
+		return elems[0], nil
+	}
+
+	count, err := args.int(1)
+	if err != nil {
+		return nil, err
+	}
+	return pop(key, count)
+}
+
+func membersCommand(args cmdArgs, f func(string, ...string) (int, error)) (any, error) {
+	if len(args) < 2 {
+		return nil, errWrongArgs
+	}
+	names, err := args.strings(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return f(names[0], names[1:]...)
+}
+
+func deref(val *any) any {
+	if val == nil {
+		return nil
+	}
+	return *val
+}
+
+// cmdArgs are the arguments of a /multi command as decoded from JSON.
+type cmdArgs []any
+
+// key returns the key of a command taking only a key.
+func (a cmdArgs) key() (string, error) {
+	if len(a) != 1 {
+		return "", errWrongArgs
+	}
+	return a.string(0)
+}
+
+func (a cmdArgs) keyValue() (string, any, error) {
+	if len(a) != 2 {
+		return "", nil, errWrongArgs
+	}
+	key, err := a.string(0)
+	return key, a[1], err
+}
+
+func (a cmdArgs) keyField() (string, string, error) {
+	key, err := a.string(0)
+	if err != nil {
+		return "", "", err
+	}
+	field, err := a.string(1)
+	return key, field, err
+}
+
+func (a cmdArgs) string(i int) (string, error) {
+	if i >= len(a) {
+		return "", errWrongArgs
+	}
+	s, ok := a[i].(string)
+	if !ok {
+		return "", errWrongArgs
+	}
+	return s, nil
+}
+
+// strings returns the arguments from i on, which must all be strings.
+func (a cmdArgs) strings(i int) ([]string, error) {
+	if i >= len(a) {
+		return nil, errWrongArgs
+	}
+
+	res := make([]string, 0, len(a)-i)
+	for j := i; j < len(a); j++ {
+		s, err := a.string(j)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+func (a cmdArgs) int(i int) (int, error) {
+	f, err := a.float(i)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+		return 0, errWrongArgs
+	}
+	return int(f), nil
+}
+
+func (a cmdArgs) float(i int) (float64, error) {
+	if i >= len(a) {
+		return 0, errWrongArgs
+	}
+	f, ok := a[i].(float64)
+	if !ok {
+		return 0, errWrongArgs
+	}
+	return f, nil
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"errors"
+	"myproj/internal/pkg/storage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Scan routes take their arguments from the query string, so that the next
+// page is a plain link: /keys/scan?match=session:*&cursor=<cursor>.
+
+// scanErrorStatus picks the response code for an error returned by a scan.
+func scanErrorStatus(err error) int {
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+
+	return storageErrorStatus(err)
+}
+
+func (r *Server) handlerKEYS(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, Entry{Value: r.storage.Keys(ctx.Query("pattern"))})
+}
+
+func (r *Server) handlerSCAN(ctx *gin.Context) {
+	var v EntryScan
+	if err := ctx.ShouldBindQuery(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	keys, cursor, err := r.storage.Scan(v.Cursor, v.Match, v.Count, storage.KeyType(v.Type))
+	if err != nil {
+		ctx.AbortWithStatusJSON(scanErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EntryPage{Value: keys, Cursor: cursor})
+}
+
+func (r *Server) handlerHSCAN(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryScan
+	if err := ctx.ShouldBindQuery(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	fields, cursor, err := r.storage.HScan(key, v.Cursor, v.Match, v.Count)
+	if err != nil {
+		ctx.AbortWithStatusJSON(scanErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EntryPage{Value: fields, Cursor: cursor})
+}
+
+func (r *Server) handlerSSCAN(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryScan
+	if err := ctx.ShouldBindQuery(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	members, cursor, err := r.storage.SScan(key, v.Cursor, v.Match, v.Count)
+	if err != nil {
+		ctx.AbortWithStatusJSON(scanErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EntryPage{Value: members, Cursor: cursor})
+}
+
+func (r *Server) handlerZSCAN(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryScan
+	if err := ctx.ShouldBindQuery(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	members, cursor, err := r.storage.ZScan(key, v.Cursor, v.Match, v.Count)
+	if err != nil {
+		ctx.AbortWithStatusJSON(scanErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EntryPage{Value: members, Cursor: cursor})
+}
+
+func (r *Server) handlerLSCAN(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryScan
+	if err := ctx.ShouldBindQuery(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	elems, cursor, err := r.storage.LScan(key, v.Cursor, v.Match, v.Count)
+	if err != nil {
+		ctx.AbortWithStatusJSON(scanErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EntryPage{Value: elems, Cursor: cursor})
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r *Server) handlerSADD(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryMembers
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.SADD(key, v.Members...)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerSREM(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	var v EntryMembers
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := r.storage.SREM(key, v.Members...)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerSISMEMBER(ctx *gin.Context) {
+	key := ctx.Param("key")
+	member := ctx.Param("member")
+
+	val, err := r.storage.SISMEMBER(key, member)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerSMEMBERS(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	val, err := r.storage.SMEMBERS(key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerSCARD(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	val, err := r.storage.SCARD(key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+// handlerSRANDMEMBER returns a single member unless the body asks for a count.
+func (r *Server) handlerSRANDMEMBER(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	v := EntryCount{Count: 1}
+	if ctx.Request.ContentLength != 0 {
+		if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+			ctx.AbortWithStatus(http.StatusBadGateway)
+			return
+		}
+	}
+
+	val, err := r.storage.SRANDMEMBER(key, v.Count)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+// handlerSPOP pops a single member unless the body asks for a count.
+func (r *Server) handlerSPOP(ctx *gin.Context) {
+	key := ctx.Param("key")
+
+	v := EntryCount{Count: 1}
+	if ctx.Request.ContentLength != 0 {
+		if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+			ctx.AbortWithStatus(http.StatusBadGateway)
+			return
+		}
+	}
+
+	val, err := r.storage.SPOP(key, v.Count)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handlerSINTER(ctx *gin.Context) {
+	r.handleSetAlgebra(ctx, r.storage.SINTER)
+}
+
+func (r *Server) handlerSUNION(ctx *gin.Context) {
+	r.handleSetAlgebra(ctx, r.storage.SUNION)
+}
+
+func (r *Server) handlerSDIFF(ctx *gin.Context) {
+	r.handleSetAlgebra(ctx, r.storage.SDIFF)
+}
+
+func (r *Server) handlerSINTERSTORE(ctx *gin.Context) {
+	r.handleSetAlgebraStore(ctx, r.storage.SINTERSTORE)
+}
+
+func (r *Server) handlerSUNIONSTORE(ctx *gin.Context) {
+	r.handleSetAlgebraStore(ctx, r.storage.SUNIONSTORE)
+}
+
+func (r *Server) handlerSDIFFSTORE(ctx *gin.Context) {
+	r.handleSetAlgebraStore(ctx, r.storage.SDIFFSTORE)
+}
+
+func (r *Server) handleSetAlgebra(ctx *gin.Context, op func(keys ...string) ([]string, error)) {
+	var v EntryKeys
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := op(v.Keys...)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
+
+func (r *Server) handleSetAlgebraStore(ctx *gin.Context, op func(dst string, keys ...string) (int, error)) {
+	key := ctx.Param("key")
+
+	var v EntryKeys
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&v); err != nil {
+		ctx.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+
+	val, err := op(key, v.Keys...)
+	if err != nil {
+		ctx.AbortWithStatusJSON(storageErrorStatus(err), gin.H{
+			"status":  "false",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, Entry{Value: val})
+}
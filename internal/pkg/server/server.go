@@ -7,9 +7,14 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// defaultSnapshotLimit is the largest snapshot upload the server reads
+// unless SetSnapshotLimit says otherwise.
+const defaultSnapshotLimit = 64 << 20
+
 type Server struct {
-	host    string
-	storage *storage.Storage
+	host          string
+	storage       *storage.Storage
+	snapshotLimit int64
 }
 
 type Entry struct {
@@ -33,15 +38,189 @@ type EntryLGET struct {
 	Index int `json:"index"`
 }
 
+type EntryRange struct {
+	Start int `json:"start"`
+	Stop  int `json:"stop"`
+}
+
+type EntryLINSERT struct {
+	Where   string `json:"where"`
+	Pivot   any    `json:"pivot"`
+	Element any    `json:"element"`
+}
+
+type EntryLREM struct {
+	Count   int `json:"count"`
+	Element any `json:"element"`
+}
+
+type EntryLPOS struct {
+	Element any `json:"element"`
+	Rank    int `json:"rank"`
+	Count   int `json:"count"`
+	MaxLen  int `json:"maxlen"`
+}
+
+type EntryBlockingPop struct {
+	Keys    []string `json:"keys"`
+	Timeout float64  `json:"timeout"`
+}
+
+type EntryPopped struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+type EntryLMove struct {
+	Source      string  `json:"source"`
+	Destination string  `json:"destination"`
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Timeout     float64 `json:"timeout"`
+}
+
+type EntryMembers struct {
+	Members []string `json:"members"`
+}
+
+type EntryKeys struct {
+	Keys []string `json:"keys"`
+}
+
+type EntryCount struct {
+	Count int `json:"count"`
+}
+
+type EntryZMembers struct {
+	Members []storage.ZMember `json:"members"`
+}
+
+type EntryZIncr struct {
+	Member    string  `json:"member"`
+	Increment float64 `json:"increment"`
+}
+
+type EntryZRange struct {
+	Start int  `json:"start"`
+	Stop  int  `json:"stop"`
+	Rev   bool `json:"rev"`
+}
+
+type EntryBounds struct {
+	Min string `json:"min"`
+	Max string `json:"max"`
+}
+
+type EntryFields struct {
+	Fields []string `json:"fields"`
+}
+
+type EntryIncr struct {
+	Delta int `json:"delta"`
+}
+
+type EntryIncrFloat struct {
+	Delta float64 `json:"delta"`
+}
+
+type EntryTTL struct {
+	Seconds int64 `json:"seconds"`
+}
+
+type EntryExpireAt struct {
+	Timestamp int64 `json:"timestamp"`
+}
+
+type EntryWithTTL struct {
+	Value   any   `json:"value"`
+	Seconds int64 `json:"seconds"`
+}
+
+type EntrySet struct {
+	Value   any   `json:"value"`
+	NX      bool  `json:"nx"`
+	XX      bool  `json:"xx"`
+	Get     bool  `json:"get"`
+	KeepTTL bool  `json:"keepttl"`
+	Seconds int64 `json:"seconds"`
+}
+
+type EntryValues struct {
+	Values map[string]any `json:"values"`
+}
+
+type EntryLists struct {
+	Lists map[string][]any `json:"lists"`
+}
+
+type EntryGetEx struct {
+	Seconds int64 `json:"seconds"`
+	Persist bool  `json:"persist"`
+}
+
+type EntryJSONPath struct {
+	Path string `json:"path"`
+}
+
+type EntryJSONSet struct {
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+type EntryJSONAppend struct {
+	Path   string `json:"path"`
+	Values []any  `json:"values"`
+}
+
+type EntryJSONIncr struct {
+	Path  string  `json:"path"`
+	Delta float64 `json:"delta"`
+}
+
+type EntryScan struct {
+	Cursor string `form:"cursor"`
+	Match  string `form:"match"`
+	Count  int    `form:"count"`
+	Type   string `form:"type"`
+}
+
+type EntryPage struct {
+	Value  any    `json:"value"`
+	Cursor string `json:"cursor"`
+}
+
+type EntryLoad struct {
+	Mode   string `form:"mode"`
+	Match  string `form:"match"`
+	DryRun bool   `form:"dryrun"`
+}
+
+type EntryCommand struct {
+	Command string `json:"command"`
+	Args    []any  `json:"args"`
+}
+
+type EntryResult struct {
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
 func New(st *storage.Storage) *Server {
 	s := &Server{
-		host:    ":8090",
-		storage: st,
+		host:          ":8090",
+		storage:       st,
+		snapshotLimit: defaultSnapshotLimit,
 	}
 
 	return s
 }
 
+// SetSnapshotLimit sets the largest snapshot upload, in bytes, the server
+// reads before answering 413.
+func (r *Server) SetSnapshotLimit(n int64) {
+	r.snapshotLimit = n
+}
+
 func (r *Server) newAPI() *gin.Engine {
 	engine := gin.New()
 
@@ -51,19 +230,106 @@ func (r *Server) newAPI() *gin.Engine {
 
 	engine.POST("/scalar/set/:key", r.handlerSet)
 	engine.GET("/scalar/get/:key", r.handlerGet)
+	engine.POST("/scalar/setex/:key", r.handlerSetWithTTL)
+	engine.POST("/scalar/getdel/:key", r.handlerGetDel)
+	engine.GET("/scalar/mget", r.handlerMGET)
+	engine.POST("/scalar/mset", r.handlerMSET)
+	engine.POST("/scalar/msetnx", r.handlerMSETNX)
+	engine.POST("/scalar/getex/:key", r.handlerGetEx)
+	engine.POST("/scalar/incr/:key", r.handlerIncr)
+	engine.POST("/scalar/decr/:key", r.handlerDecr)
+	engine.POST("/scalar/incrby/:key", r.handlerIncrBy)
+	engine.POST("/scalar/incrbyfloat/:key", r.handlerIncrByFloat)
 
 	engine.POST("/hash/set/:key/:field", r.handlerHSET)
 	engine.POST("hash/get/:key/:field", r.handlerHGET)
+	engine.GET("/hash/get/:key/:field", r.handlerHGET)
+	engine.POST("/hash/del/:key", r.handlerHDEL)
+	engine.GET("/hash/getall/:key", r.handlerHGETALL)
+	engine.GET("/hash/keys/:key", r.handlerHKEYS)
+	engine.GET("/hash/vals/:key", r.handlerHVALS)
+	engine.GET("/hash/len/:key", r.handlerHLEN)
+	engine.GET("/hash/exists/:key/:field", r.handlerHEXISTS)
+	engine.POST("/hash/incrby/:key/:field", r.handlerHINCRBY)
 
 	engine.POST("/array/lpush/:key", r.handlerLPUSH)
 	engine.GET("/array/lpop/:key", r.handlerLPOP)
 
 	engine.POST("/array/rpush/:key", r.handlerRPUSH)
+	engine.POST("/array/mpush", r.handlerMPUSH)
 	engine.POST("/array/raddtoset/:key", r.handlerRADDTOSET)
 	engine.GET("/array/rpop/:key", r.handlerRPOP)
 
 	engine.POST("/array/lset/:key", r.handlerLSET)
 	engine.GET("/array/lget/:key", r.handlerLGET)
 
+	engine.GET("/array/lrange/:key", r.handlerLRANGE)
+	engine.GET("/array/llen/:key", r.handlerLLEN)
+	engine.GET("/array/lindex/:key", r.handlerLINDEX)
+	engine.POST("/array/linsert/:key", r.handlerLINSERT)
+	engine.POST("/array/lrem/:key", r.handlerLREM)
+	engine.POST("/array/ltrim/:key", r.handlerLTRIM)
+	engine.GET("/array/lpos/:key", r.handlerLPOS)
+
+	engine.POST("/array/blpop", r.handlerBLPOP)
+	engine.POST("/array/brpop", r.handlerBRPOP)
+	engine.POST("/array/lmove", r.handlerLMOVE)
+	engine.POST("/array/rpoplpush", r.handlerRPOPLPUSH)
+	engine.POST("/array/blmove", r.handlerBLMOVE)
+
+	engine.POST("/set/add/:key", r.handlerSADD)
+	engine.POST("/set/rem/:key", r.handlerSREM)
+	engine.GET("/set/ismember/:key/:member", r.handlerSISMEMBER)
+	engine.GET("/set/members/:key", r.handlerSMEMBERS)
+	engine.GET("/set/card/:key", r.handlerSCARD)
+	engine.GET("/set/randmember/:key", r.handlerSRANDMEMBER)
+	engine.POST("/set/pop/:key", r.handlerSPOP)
+	engine.GET("/set/inter", r.handlerSINTER)
+	engine.GET("/set/union", r.handlerSUNION)
+	engine.GET("/set/diff", r.handlerSDIFF)
+	engine.POST("/set/interstore/:key", r.handlerSINTERSTORE)
+	engine.POST("/set/unionstore/:key", r.handlerSUNIONSTORE)
+	engine.POST("/set/diffstore/:key", r.handlerSDIFFSTORE)
+
+	engine.POST("/zset/add/:key", r.handlerZADD)
+	engine.POST("/zset/incrby/:key", r.handlerZINCRBY)
+	engine.GET("/zset/score/:key/:member", r.handlerZSCORE)
+	engine.GET("/zset/card/:key", r.handlerZCARD)
+	engine.GET("/zset/rank/:key/:member", r.handlerZRANK)
+	engine.GET("/zset/revrank/:key/:member", r.handlerZREVRANK)
+	engine.GET("/zset/range/:key", r.handlerZRANGE)
+	engine.GET("/zset/rangebyscore/:key", r.handlerZRANGEBYSCORE)
+	engine.GET("/zset/rangebylex/:key", r.handlerZRANGEBYLEX)
+	engine.POST("/zset/rem/:key", r.handlerZREM)
+	engine.POST("/zset/remrangebyrank/:key", r.handlerZREMRANGEBYRANK)
+	engine.POST("/zset/remrangebyscore/:key", r.handlerZREMRANGEBYSCORE)
+
+	engine.GET("/json/get/:key", r.handlerJSONGET)
+	engine.POST("/json/set/:key", r.handlerJSONSET)
+	engine.POST("/json/del/:key", r.handlerJSONDEL)
+	engine.POST("/json/arrappend/:key", r.handlerJSONARRAPPEND)
+	engine.POST("/json/numincrby/:key", r.handlerJSONNUMINCRBY)
+
+	engine.GET("/keys", r.handlerKEYS)
+	engine.GET("/keys/scan", r.handlerSCAN)
+	engine.GET("/hash/scan/:key", r.handlerHSCAN)
+	engine.GET("/set/scan/:key", r.handlerSSCAN)
+	engine.GET("/zset/scan/:key", r.handlerZSCAN)
+	engine.GET("/array/scan/:key", r.handlerLSCAN)
+
+	engine.POST("/multi", r.handlerMULTI)
+
+	engine.POST("/key/expire/:key", r.handlerExpire)
+	engine.POST("/key/expireat/:key", r.handlerExpireAt)
+	engine.GET("/key/ttl/:key", r.handlerTTL)
+	engine.POST("/key/persist/:key", r.handlerPersist)
+	engine.POST("/key/del/:key", r.handlerDel)
+	engine.GET("/key/exists/:key", r.handlerExists)
+	engine.GET("/key/type/:key", r.handlerType)
+
+	engine.GET("/admin/aof", r.handlerAOFStatus)
+	engine.POST("/admin/aof/rewrite", r.handlerAOFRewrite)
+	engine.POST("/admin/snapshot/load", r.handlerSnapshotLoad)
+
 	return engine
 }
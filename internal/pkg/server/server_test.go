@@ -4,10 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"myproj/internal/pkg/storage"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -39,7 +44,7 @@ func TestSET(t *testing.T) {
 	testKey := []string{"key1", "key2", "key3"}
 	testVal := []any{"hello", 1221, 1221.07}
 
-	expected := []any{http.StatusOK, http.StatusOK, http.StatusBadGateway}
+	expected := []any{http.StatusOK, http.StatusOK, http.StatusOK}
 
 	for i, k := range testKey {
 		testVal := Entry{
@@ -88,6 +93,51 @@ func TestGET(t *testing.T) {
 
 }
 
+func TestGETValueKinds(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+
+	testKeys := []string{"key4", "key5", "key6", "key7"}
+	testVals := []any{
+		12.5,
+		true,
+		nil,
+		map[string]any{"name": "doc", "tags": []any{"a", float64(1)}},
+	}
+
+	for i, k := range testKeys {
+		testVal := Entry{
+			Value: testVals[i],
+		}
+
+		jsonVal, _ := json.MarshalIndent(testVal, "", "\t")
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/scalar/set/"+k, bytes.NewBuffer(jsonVal))
+		serve.newAPI().ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "/scalar/get/"+k, nil)
+		serve.newAPI().ServeHTTP(w, req)
+
+		var val Entry
+		json.Unmarshal(w.Body.Bytes(), &val)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, val.Value, testVals[i])
+	}
+
+	// null must be sent explicitly, a body without a value is rejected
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/scalar/set/key8", bytes.NewBufferString("{}"))
+	serve.newAPI().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
 func TestHSET(t *testing.T) {
 	store, err := storage.NewStorage()
 	if err != nil {
@@ -97,7 +147,7 @@ func TestHSET(t *testing.T) {
 
 	testkeys := []string{"key1", "key2", "key3"}
 	testVals := []any{123, "val2", 123.05}
-	expectedCodes := []any{http.StatusOK, http.StatusOK, http.StatusBadGateway}
+	expectedCodes := []any{http.StatusOK, http.StatusOK, http.StatusOK}
 	for idx, key := range testkeys {
 		testVal := Entry{
 			Value: testVals[idx],
@@ -157,7 +207,7 @@ func TestLPUSH(t *testing.T) {
 		{1, 2, 3, 5, 24.8},
 	}
 
-	expected := []any{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusBadGateway}
+	expected := []any{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK}
 
 	for i, k := range testKeys {
 		testVal := EntryArray{
@@ -245,7 +295,7 @@ func TestRPUSH(t *testing.T) {
 		{1, 2, 3, 5, 24.8},
 	}
 
-	expected := []any{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusBadGateway}
+	expected := []any{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK}
 
 	for i, k := range testKeys {
 		testVal := EntryArray{
@@ -476,3 +526,967 @@ func TestLGET(t *testing.T) {
 		assert.Equal(t, testArgs[i][1], val.Value)
 	}
 }
+
+func TestExpire(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	jsonVal, _ := json.Marshal(EntryWithTTL{Value: "session", Seconds: 100})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/scalar/setex/key1", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/key/ttl/key1", nil)
+	api.ServeHTTP(w, req)
+
+	var val Entry
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(100), val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/key/persist/key1", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/key/ttl/key1", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(-1), val.Value)
+
+	jsonVal, _ = json.Marshal(EntryTTL{Seconds: 0})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/key/expire/key1", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/scalar/get/key1", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	jsonVal, _ = json.Marshal(EntryTTL{Seconds: 10})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/key/expire/missing", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestKeyspace(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	jsonVal, _ := json.Marshal(EntryArray{Value: []any{1, 2}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/array/rpush/key1", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	jsonVal, _ = json.Marshal(Entry{Value: "value"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/set/key1", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var val Entry
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/key/type/key1", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, "list", val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/key/del/key1", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(1), val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/key/exists/key1", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, false, val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/key/type/key1", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, "none", val.Value)
+}
+
+func TestHashCommands(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	for field, value := range map[string]any{"name": "ann", "visits": 1} {
+		jsonVal, _ := json.Marshal(Entry{Value: value})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/hash/set/user/"+field, bytes.NewBuffer(jsonVal))
+		api.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	jsonVal, _ := json.Marshal(EntryIncr{Delta: 2})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/hash/incrby/user/visits", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+
+	var val Entry
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(3), val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/hash/getall/user", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, map[string]any{"name": "ann", "visits": float64(3)}, val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/hash/keys/user", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, []any{"name", "visits"}, val.Value)
+
+	jsonVal, _ = json.Marshal(EntryFields{Fields: []string{"name"}})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/hash/del/user", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(1), val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/hash/len/user", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(1), val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/hash/exists/user/name", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, false, val.Value)
+}
+
+func TestCounters(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	var val Entry
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/scalar/incr/views", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(1), val.Value)
+
+	jsonVal, _ := json.Marshal(EntryIncr{Delta: 9})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/incrby/views", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(10), val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/decr/views", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(9), val.Value)
+
+	jsonVal, _ = json.Marshal(EntryIncrFloat{Delta: 0.25})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/incrbyfloat/views", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, 9.25, val.Value)
+
+	jsonVal, _ = json.Marshal(Entry{Value: "text"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/set/name", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/incr/name", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+func TestListCommands(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	jsonVal, _ := json.Marshal(EntryArray{Value: []any{"e1", "e2", "e3", "e4", "e5"}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/array/rpush/events", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	jsonVal, _ = json.Marshal(EntryRange{Start: -3, Stop: -1})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/array/ltrim/events", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	jsonVal, _ = json.Marshal(EntryLINSERT{Where: "before", Pivot: "e4", Element: "x"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/array/linsert/events", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var val Entry
+	jsonVal, _ = json.Marshal(EntryRange{Start: 0, Stop: -1})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/array/lrange/events", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []any{"e3", "x", "e4", "e5"}, val.Value)
+
+	jsonVal, _ = json.Marshal(EntryLPOS{Element: "e4"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/array/lpos/events", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, []any{float64(2)}, val.Value)
+
+	jsonVal, _ = json.Marshal(EntryLREM{Count: 0, Element: "x"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/array/lrem/events", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(1), val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/array/llen/events", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(3), val.Value)
+
+	jsonVal, _ = json.Marshal(EntryLGET{Index: 5})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/array/lindex/events", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestBlockingPop(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		store.RPUSH("queue", []any{"job"})
+	}()
+
+	jsonVal, _ := json.Marshal(EntryBlockingPop{Keys: []string{"other", "queue"}, Timeout: 1})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/array/blpop", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+
+	var val EntryPopped
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, EntryPopped{Key: "queue", Value: "job"}, val)
+
+	jsonVal, _ = json.Marshal(EntryBlockingPop{Keys: []string{"queue"}, Timeout: 0.05})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/array/brpop", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestLMove(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	store.RPUSH("queue", []any{"job1", "job2"})
+
+	var val Entry
+	jsonVal, _ := json.Marshal(EntryLMove{Source: "queue", Destination: "processing", From: "left", To: "right"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/array/lmove", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "job1", val.Value)
+
+	jsonVal, _ = json.Marshal(EntryLMove{Source: "queue", Destination: "processing"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/array/rpoplpush", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, "job2", val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/array/rpoplpush", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	jsonVal, _ = json.Marshal(EntryLMove{Source: "queue", Destination: "processing", From: "left", To: "left", Timeout: 0.05})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/array/blmove", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestSetCommands(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	var val Entry
+	for key, members := range map[string][]string{"doc1": {"go", "db", "web"}, "doc2": {"go", "web"}} {
+		jsonVal, _ := json.Marshal(EntryMembers{Members: members})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/set/add/"+key, bytes.NewBuffer(jsonVal))
+		api.ServeHTTP(w, req)
+		json.Unmarshal(w.Body.Bytes(), &val)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, float64(len(members)), val.Value)
+	}
+
+	jsonVal, _ := json.Marshal(EntryKeys{Keys: []string{"doc1", "doc2"}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/set/inter", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, []any{"go", "web"}, val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/set/diffstore/only1", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(1), val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/set/ismember/only1/db", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, true, val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/set/pop/only1", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, []any{"db"}, val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/set/card/only1", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(0), val.Value)
+}
+
+func TestZSetCommands(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	var val Entry
+	jsonVal, _ := json.Marshal(EntryZMembers{Members: []storage.ZMember{
+		{Member: "ann", Score: 10},
+		{Member: "bob", Score: 20},
+		{Member: "cid", Score: 30},
+	}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/zset/add/board", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(3), val.Value)
+
+	jsonVal, _ = json.Marshal(EntryZIncr{Member: "ann", Increment: 25})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/zset/incrby/board", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(35), val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/zset/revrank/board/ann", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(0), val.Value)
+
+	jsonVal, _ = json.Marshal(EntryZRange{Start: 0, Stop: 1, Rev: true})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/zset/range/board", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, []any{
+		map[string]any{"member": "ann", "score": float64(35)},
+		map[string]any{"member": "cid", "score": float64(30)},
+	}, val.Value)
+
+	jsonVal, _ = json.Marshal(EntryBounds{Min: "(20", Max: "+inf"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/zset/remrangebyscore/board", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(2), val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/zset/score/board/ann", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestJSONCommands(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	var val Entry
+	jsonVal, _ := json.Marshal(EntryJSONSet{Path: "$", Value: map[string]any{
+		"name":  "api",
+		"hosts": []any{"a"},
+		"port":  8080,
+	}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/json/set/config", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	jsonVal, _ = json.Marshal(EntryJSONSet{Path: "$.name", Value: "web"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/json/set/config", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	jsonVal, _ = json.Marshal(EntryJSONAppend{Path: "$.hosts", Values: []any{"b"}})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/json/arrappend/config", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(2), val.Value)
+
+	jsonVal, _ = json.Marshal(EntryJSONIncr{Path: "$.port", Delta: 1})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/json/numincrby/config", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(8081), val.Value)
+
+	jsonVal, _ = json.Marshal(EntryJSONPath{Path: "$.hosts[0]"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/json/del/config", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, float64(1), val.Value)
+
+	jsonVal, _ = json.Marshal(EntryJSONPath{Path: "$"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/json/get/config", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, map[string]any{
+		"name":  "web",
+		"hosts": []any{"b"},
+		"port":  float64(8081),
+	}, val.Value)
+
+	jsonVal, _ = json.Marshal(EntryJSONSet{Path: "$.a.b", Value: 1})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/json/set/config", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	jsonVal, _ = json.Marshal(EntryJSONPath{Path: "name"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/json/get/config", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestScanKeys(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	store.Set("session:1", "a")
+	store.Set("session:2", "b")
+	store.Set("session:3", "c")
+	store.Set("user:1", "d")
+	store.HSET("hash", "f1", 1)
+
+	var val Entry
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/keys?pattern=session:*", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []any{"session:1", "session:2", "session:3"}, val.Value)
+
+	var page EntryPage
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/keys/scan?match=session:*&count=2", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, []any{"session:1", "session:2"}, page.Value)
+	assert.NotEmpty(t, page.Cursor)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/keys/scan?match=session:*&count=2&cursor="+page.Cursor, nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, []any{"session:3"}, page.Value)
+	assert.Empty(t, page.Cursor)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/hash/scan/hash", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(t, map[string]any{"f1": float64(1)}, page.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/keys/scan?cursor=!!", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/set/scan/hash", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestMulti(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	store.Set("balance:alice", 100)
+
+	var res struct {
+		Results []EntryResult `json:"results"`
+		Index   int           `json:"index"`
+	}
+	jsonVal, _ := json.Marshal([]EntryCommand{
+		{Command: "INCRBY", Args: []any{"balance:alice", -30}},
+		{Command: "incrby", Args: []any{"balance:bob", 30}},
+		{Command: "RPUSH", Args: []any{"log", "alice->bob"}},
+		{Command: "GET", Args: []any{"balance:alice"}},
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/multi", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &res)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []EntryResult{
+		{Result: float64(70)},
+		{Result: float64(30)},
+		{Result: float64(1)},
+		{Result: float64(70)},
+	}, res.Results)
+
+	// the HSET on a string fails, so the INCRBY before it is rolled back
+	jsonVal, _ = json.Marshal([]EntryCommand{
+		{Command: "INCRBY", Args: []any{"balance:alice", -30}},
+		{Command: "HSET", Args: []any{"balance:bob", "f", 1}},
+	})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/multi", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &res)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, 1, res.Index)
+	assert.Equal(t, float64(40), res.Results[0].Result)
+	assert.NotEmpty(t, res.Results[1].Error)
+	assert.Equal(t, 70, *store.Get("balance:alice"))
+
+	jsonVal, _ = json.Marshal([]EntryCommand{
+		{Command: "SET", Args: []any{"key", "value"}},
+		{Command: "FLUSHALL"},
+	})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/multi", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Zero(t, store.Exists("key"))
+
+	jsonVal, _ = json.Marshal([]EntryCommand{
+		{Command: "INCRBY", Args: []any{"balance:alice", "ten"}},
+	})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/multi", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestETag(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	jsonVal, _ := json.Marshal(Entry{Value: "a"})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/scalar/set/key", bytes.NewBuffer(jsonVal))
+	req.Header.Set("If-None-Match", "*")
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	created := w.Header().Get("ETag")
+	assert.NotEmpty(t, created)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/scalar/get/key", nil)
+	api.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, created, etag)
+
+	jsonVal, _ = json.Marshal(Entry{Value: "b"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/set/key", bytes.NewBuffer(jsonVal))
+	req.Header.Set("If-Match", etag)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	// a second writer holding the old tag is turned away
+	jsonVal, _ = json.Marshal(Entry{Value: "c"})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/set/key", bytes.NewBuffer(jsonVal))
+	req.Header.Set("If-Match", etag)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, "b", *store.Get("key"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/set/key", bytes.NewBuffer(jsonVal))
+	req.Header.Set("If-None-Match", "*")
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/set/key", bytes.NewBuffer(jsonVal))
+	req.Header.Set("If-Match", `W/"1"`)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestSetOptions(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	jsonVal, _ := json.Marshal(EntrySet{Value: "owner-1", NX: true, Seconds: 60})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/scalar/set/lock", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Greater(t, store.TTL("lock"), time.Duration(0))
+
+	jsonVal, _ = json.Marshal(EntrySet{Value: "owner-2", NX: true, Get: true})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/set/lock", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	var val Entry
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Equal(t, "owner-1", val.Value)
+
+	jsonVal, _ = json.Marshal(EntrySet{Value: "owner-2", XX: true, KeepTTL: true})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/set/lock", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Greater(t, store.TTL("lock"), time.Duration(0))
+
+	jsonVal, _ = json.Marshal(EntryGetEx{Persist: true})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/getex/lock", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "owner-2", val.Value)
+	assert.Equal(t, storage.TTLNoExpire, store.TTL("lock"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/getdel/lock", nil)
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "owner-2", val.Value)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/getdel/lock", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	jsonVal, _ = json.Marshal(EntrySet{Value: 1, NX: true, XX: true})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/set/lock", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+func TestMSetMGet(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	jsonVal, _ := json.Marshal(EntryValues{Values: map[string]any{"a": 1, "b": "two"}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/scalar/mset", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var val Entry
+	jsonVal, _ = json.Marshal(EntryKeys{Keys: []string{"a", "missing", "b"}})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/scalar/mget", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &val)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []any{float64(1), nil, "two"}, val.Value)
+
+	jsonVal, _ = json.Marshal(EntryValues{Values: map[string]any{"b": 2, "c": 3}})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/msetnx", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Nil(t, store.Get("c"))
+
+	jsonVal, _ = json.Marshal(EntryValues{Values: map[string]any{"c": 3, "d": 4}})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/msetnx", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	store.SADD("set", "x")
+	jsonVal, _ = json.Marshal(EntryValues{Values: map[string]any{"set": 1}})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/scalar/mset", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestMPush(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	jsonVal, _ := json.Marshal(EntryLists{Lists: map[string][]any{"a": {1, 2}, "b": {"x"}}})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/array/mpush", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	a, _ := store.LRANGE("a", 0, -1)
+	b, _ := store.LRANGE("b", 0, -1)
+	assert.Equal(t, []any{float64(1), float64(2)}, a)
+	assert.Equal(t, []any{"x"}, b)
+
+	store.Set("scalar", 1)
+	jsonVal, _ = json.Marshal(EntryLists{Lists: map[string][]any{"c": {1}, "scalar": {1}}})
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/array/mpush", bytes.NewBuffer(jsonVal))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, 0, store.Exists("c"))
+}
+
+func TestAOFRewrite(t *testing.T) {
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/admin/aof/rewrite", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	assert.NoError(t, store.OpenAOF(filepath.Join(t.TempDir(), "appendonly.aof"), storage.FsyncNo))
+	defer store.CloseAOF()
+	store.SetAOFAutoRewrite(0, 0)
+	for i := 0; i < 100; i++ {
+		store.Incr("counter")
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/admin/aof/rewrite", nil)
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	var status storage.AOFStatus
+	assert.Eventually(t, func() bool {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "/admin/aof", nil)
+		api.ServeHTTP(w, req)
+		json.Unmarshal(w.Body.Bytes(), &status)
+		return !status.Rewriting
+	}, 5*time.Second, time.Millisecond)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, 1, status.Rewrites)
+	assert.Equal(t, status.BaseSize, status.Size)
+}
+
+func TestSnapshotLoad(t *testing.T) {
+	src, _ := storage.NewStorage()
+	src.Set("t1:a", "from file")
+	src.Set("t2:a", "from file")
+	path := filepath.Join(t.TempDir(), "dump.snap")
+	assert.NoError(t, src.SaveSnapshot(path, storage.FormatBinary))
+	snapshot, _ := os.ReadFile(path)
+
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+	store.Set("t1:a", "current")
+	store.Set("t2:a", "current")
+
+	serve := New(&store)
+	api := serve.newAPI()
+
+	var report storage.LoadReport
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/admin/snapshot/load?mode=merge-overwrite&match=t1:*&dryrun=true", bytes.NewBuffer(snapshot))
+	api.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &report)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, storage.LoadReport{DryRun: true, Overwritten: []string{"t1:a"}, Skipped: 1}, report)
+	assert.Equal(t, "current", *store.Get("t1:a"))
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, _ := mw.CreateFormFile("snapshot", "dump.snap")
+	part.Write(snapshot)
+	mw.Close()
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/admin/snapshot/load?mode=merge-overwrite&match=t1:*", &form)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "from file", *store.Get("t1:a"))
+	assert.Equal(t, "current", *store.Get("t2:a"))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/admin/snapshot/load", bytes.NewBufferString("not a snapshot"))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "current", *store.Get("t2:a"))
+}
+
+func TestSnapshotLoadLimit(t *testing.T) {
+	src, _ := storage.NewStorage()
+	src.Set("t1:a", strings.Repeat("x", 4096))
+	path := filepath.Join(t.TempDir(), "dump.snap")
+	assert.NoError(t, src.SaveSnapshot(path, storage.FormatBinary))
+	snapshot, _ := os.ReadFile(path)
+
+	store, err := storage.NewStorage()
+	if err != nil {
+		t.Errorf("Initialize error")
+	}
+	store.Set("t1:a", "current")
+
+	serve := New(&store)
+	serve.SetSnapshotLimit(1024)
+	api := serve.newAPI()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/admin/snapshot/load", bytes.NewBuffer(snapshot))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "current", *store.Get("t1:a"))
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	part, _ := mw.CreateFormFile("snapshot", "dump.snap")
+	part.Write(snapshot)
+	mw.Close()
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/admin/snapshot/load", &form)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "current", *store.Get("t1:a"))
+
+	serve.SetSnapshotLimit(1 << 20)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/admin/snapshot/load", bytes.NewBuffer(snapshot))
+	api.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, strings.Repeat("x", 4096), *store.Get("t1:a"))
+}
@@ -0,0 +1,755 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FsyncPolicy tells the append-only file when to sync records to disk.
+type FsyncPolicy string
+
+const (
+	// FsyncAlways syncs every record before the command returns.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncEverySec syncs once a second, a crash loses at most the last second.
+	FsyncEverySec FsyncPolicy = "everysec"
+	// FsyncNo hands records to the operating system and lets it sync them.
+	FsyncNo FsyncPolicy = "no"
+)
+
+// aofSyncInterval is how often FsyncEverySec syncs the file.
+const aofSyncInterval = time.Second
+
+// ErrAOFOpen is returned by OpenAOF when the storage already logs to a file.
+var ErrAOFOpen = errors.New("append-only file already open")
+
+// appendOnlyFile logs every change to the storage as the command replaying
+// it, one JSON array per line. The commands of a transaction are written
+// between "MULTI" and "EXEC" records so that they are replayed all or none.
+type appendOnlyFile struct {
+	// on is read by every write command, it saves them the mutex when the
+	// storage does not log.
+	on     atomic.Bool
+	mu     sync.Mutex
+	file   *os.File
+	path   string
+	policy FsyncPolicy
+	dirty  bool
+	done   chan struct{}
+	// size is the length of the file, baseSize what it was after the last
+	// rewrite or when it was opened, for the growth rewrites are started on.
+	size     int64
+	baseSize int64
+	rewrites rewriteState
+	logger   *zap.Logger
+}
+
+// OpenAOF replays the append-only file at path, creating it if needed, and
+// logs every later change to it. A last record cut short by a crash is
+// dropped from the file, any other damage fails the replay.
+//
+// Changes made while the file is replayed are not logged, so OpenAOF should
+// be called before the storage is handed to clients.
+func (s *Storage) OpenAOF(path string, policy FsyncPolicy) error {
+	switch policy {
+	case FsyncAlways, FsyncEverySec, FsyncNo:
+	default:
+		return fmt.Errorf("unknown fsync policy %q", policy)
+	}
+	if s.tx != nil {
+		return ErrInTransaction
+	}
+	if s.aof.on.Load() {
+		return ErrAOFOpen
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open append-only file: %w", err)
+	}
+
+	replayed, err := s.replayAOF(file)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to replay append-only file: %w", err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat append-only file: %w", err)
+	}
+
+	a := s.aof
+	a.mu.Lock()
+	a.file = file
+	a.path = path
+	a.policy = policy
+	a.size = stat.Size()
+	a.baseSize = stat.Size()
+	a.rewrites.start = s.rewriteAOF
+	if policy == FsyncEverySec {
+		a.done = make(chan struct{})
+		go a.syncEverySecond(a.done)
+	}
+	a.mu.Unlock()
+	a.on.Store(true)
+
+	s.logger.Info("append-only file opened",
+		zap.String("file", path),
+		zap.String("fsync", string(policy)),
+		zap.Int("records_replayed", replayed))
+	return nil
+}
+
+// CloseAOF syncs and closes the append-only file. Later changes are not
+// logged. A rewrite in progress is abandoned.
+func (s *Storage) CloseAOF() error {
+	a := s.aof
+	a.on.Store(false)
+
+	a.mu.Lock()
+	if a.file == nil {
+		a.mu.Unlock()
+		return nil
+	}
+	if a.done != nil {
+		close(a.done)
+		a.done = nil
+	}
+
+	err := a.file.Sync()
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	a.file = nil
+	rewriteDone := a.rewrites.done
+	a.mu.Unlock()
+
+	// the rewrite sees the file is gone and removes its own
+	if rewriteDone != nil {
+		<-rewriteDone
+	}
+
+	return err
+}
+
+// propagate logs a change as the command that replays it. It must be called
+// with the shards of the changed keys still locked, so that the file keeps
+// the changes to a key in the order they happened. Inside a transaction the
+// command is held back until it commits.
+func (s *Storage) propagate(args ...any) {
+	s.dirty.Add(1)
+	if !s.aof.on.Load() {
+		return
+	}
+
+	if s.tx != nil {
+		s.tx.records = append(s.tx.records, args)
+		return
+	}
+	s.aof.append(args)
+}
+
+// propagateSet logs the value and the deadline a scalar key ended up with.
+// It must be called with sh.mu held.
+func (s *Storage) propagateSet(sh *shard, key string) {
+	s.propagate("SET", key, sh.inner[key].Val, sh.innerExpire[key])
+}
+
+// propagateKeys logs the keys as they are, after a change too wide to log
+// command by command or one that must be replayed all or none. It must be
+// called with the shards of the keys locked.
+func (s *Storage) propagateKeys(keys []string) {
+	s.dirty.Add(int64(len(keys)))
+	if !s.aof.on.Load() {
+		return
+	}
+
+	records := make([][]any, 0, 2*len(keys))
+	for _, key := range keys {
+		records = append(records, []any{"DEL", key})
+		records = append(records, s.shard(key).keyRecords(key)...)
+	}
+	s.logAll(records)
+}
+
+// propagateAll logs records that replay must apply all or none of. It must be
+// called with the shards of their keys locked.
+func (s *Storage) propagateAll(records [][]any) {
+	s.dirty.Add(int64(len(records)))
+	s.logAll(records)
+}
+
+func (s *Storage) logAll(records [][]any) {
+	if !s.aof.on.Load() {
+		return
+	}
+
+	if s.tx != nil {
+		s.tx.records = append(s.tx.records, records...)
+		return
+	}
+	s.aof.appendTx(records)
+}
+
+// keyRecords returns the records that build the key as it is. It must be
+// called with sh.mu held.
+func (sh *shard) keyRecords(key string) [][]any {
+	k := sh.snapshotKey(key, sh.keyType(key))
+	return k.records()
+}
+
+// records returns the records that build the key.
+func (k *snapshotKey) records() [][]any {
+	var records [][]any
+
+	switch k.Type {
+	case TypeString:
+		return [][]any{{"SET", k.Key, k.Value.Val, k.ExpireAt}}
+	case TypeList:
+		records = append(records, append([]any{"RPUSH", k.Key}, k.List...))
+	case TypeHash:
+		fields := make([]string, 0, len(k.Hash))
+		for field := range k.Hash {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		rec := []any{"HSET", k.Key}
+		for _, field := range fields {
+			rec = append(rec, field, k.Hash[field].Val)
+		}
+		records = append(records, rec)
+	case TypeSet:
+		records = append(records, append([]any{"SADD", k.Key}, stringArgs(k.Set)...))
+	case TypeZSet:
+		rec := []any{"ZADD", k.Key}
+		for _, m := range k.ZSet {
+			rec = append(rec, scoreArg(float64(m.Score)), m.Member)
+		}
+		records = append(records, rec)
+	}
+
+	if k.ExpireAt != 0 {
+		records = append(records, []any{"PEXPIREAT", k.Key, k.ExpireAt})
+	}
+	return records
+}
+
+func stringArgs(strs []string) []any {
+	res := make([]any, len(strs))
+	for i, str := range strs {
+		res[i] = str
+	}
+	return res
+}
+
+func intArgs(ints []int) []any {
+	res := make([]any, len(ints))
+	for i, n := range ints {
+		res[i] = n
+	}
+	return res
+}
+
+// scoreArg writes infinite scores as strings, which JSON has no number for.
+func scoreArg(score float64) any {
+	switch {
+	case math.IsInf(score, 1):
+		return "+inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+	return score
+}
+
+func zaddArgs(key string, members []ZMember) []any {
+	res := make([]any, 0, 2+2*len(members))
+	res = append(res, "ZADD", key)
+	for _, m := range members {
+		res = append(res, scoreArg(m.Score), m.Member)
+	}
+	return res
+}
+
+// append writes records in one go and syncs them as the policy says.
+func (a *appendOnlyFile) append(records ...[]any) {
+	if !a.on.Load() || len(records) == 0 {
+		return
+	}
+
+	var buf bytes.Buffer
+	for _, rec := range records {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			a.logger.Error("record not logged", zap.Error(err))
+			return
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return
+	}
+	n, err := a.file.Write(buf.Bytes())
+	a.size += int64(n)
+	if err != nil {
+		a.logger.Error("append-only file write failed", zap.Error(err))
+		return
+	}
+	if a.rewrites.buf != nil {
+		a.rewrites.buf.Write(buf.Bytes())
+	}
+	a.rewriteIfGrown()
+
+	switch a.policy {
+	case FsyncAlways:
+		if err := a.file.Sync(); err != nil {
+			a.logger.Error("append-only file sync failed", zap.Error(err))
+		}
+	case FsyncEverySec:
+		a.dirty = true
+	}
+}
+
+// appendTx writes the records of a committed transaction.
+func (a *appendOnlyFile) appendTx(records [][]any) {
+	if len(records) > 1 {
+		records = append(append([][]any{{"MULTI"}}, records...), []any{"EXEC"})
+	}
+	a.append(records...)
+}
+
+func (a *appendOnlyFile) syncEverySecond(done chan struct{}) {
+	ticker := time.NewTicker(aofSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+
+		a.mu.Lock()
+		if a.dirty && a.file != nil {
+			if err := a.file.Sync(); err != nil {
+				a.logger.Error("append-only file sync failed", zap.Error(err))
+			}
+			a.dirty = false
+		}
+		a.mu.Unlock()
+	}
+}
+
+// replayAOF applies the records of file and returns how many there were. A
+// truncated last record, or transaction without its "EXEC", is cut off the file.
+func (s *Storage) replayAOF(file *os.File) (int, error) {
+	r := bufio.NewReader(file)
+
+	var (
+		offset, good int64
+		replayed     int
+		pending      [][]any
+		inMulti      bool
+	)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// a last line without its newline was cut short while written
+			break
+		}
+		if err != nil {
+			return replayed, err
+		}
+		offset += int64(len(line))
+
+		rec, err := decodeRecord(line)
+		if err != nil {
+			if _, peekErr := r.Peek(1); peekErr == io.EOF {
+				break
+			}
+			return replayed, fmt.Errorf("record at byte %d: %w", offset-int64(len(line)), err)
+		}
+
+		switch rec[0] {
+		case "MULTI":
+			inMulti = true
+			continue
+		case "EXEC":
+			inMulti = false
+		default:
+			pending = append(pending, rec)
+			if inMulti {
+				continue
+			}
+		}
+
+		for _, rec := range pending {
+			if err := s.applyRecord(rec); err != nil {
+				return replayed, fmt.Errorf("record %v: %w", rec, err)
+			}
+			replayed++
+		}
+		pending = pending[:0]
+		good = offset
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		return replayed, err
+	}
+	if stat.Size() > good {
+		if err := file.Truncate(good); err != nil {
+			return replayed, err
+		}
+		s.logger.Warn("truncated record dropped from append-only file",
+			zap.Int64("bytes", stat.Size()-good))
+	}
+
+	return replayed, nil
+}
+
+func decodeRecord(line []byte) ([]any, error) {
+	val, err := decodeJSONValue(line)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, ok := val.([]any)
+	if !ok || len(rec) == 0 {
+		return nil, errors.New("not a command")
+	}
+	if _, ok := rec[0].(string); !ok {
+		return nil, errors.New("not a command")
+	}
+
+	return rec, nil
+}
+
+func (s *Storage) applyRecord(rec []any) error {
+	replay, ok := aofCommands[rec[0].(string)]
+	if !ok {
+		return fmt.Errorf("unknown command %q", rec[0])
+	}
+
+	return replay(s, &recordArgs{args: rec[1:]})
+}
+
+// aofCommands replays the records propagate writes. Each one runs the
+// command of the same name, with relative deadlines and random picks already
+// turned into what they resolved to.
+var aofCommands = map[string]func(s *Storage, a *recordArgs) error{
+	"SET": func(s *Storage, a *recordArgs) error {
+		key, val, at := a.string(0), a.value(1), a.int64(2)
+		if a.err != nil {
+			return a.err
+		}
+		if err := s.Set(key, val); err != nil {
+			return err
+		}
+		if at > 0 {
+			s.ExpireAt(key, time.UnixMilli(at))
+		}
+		return nil
+	},
+	"DEL": func(s *Storage, a *recordArgs) error {
+		keys := a.strings(0)
+		if a.err != nil {
+			return a.err
+		}
+		s.Del(keys...)
+		return nil
+	},
+	"PEXPIREAT": func(s *Storage, a *recordArgs) error {
+		key, at := a.string(0), a.int64(1)
+		if a.err != nil {
+			return a.err
+		}
+		s.ExpireAt(key, time.UnixMilli(at))
+		return nil
+	},
+	"PERSIST": func(s *Storage, a *recordArgs) error {
+		key := a.string(0)
+		if a.err != nil {
+			return a.err
+		}
+		s.Persist(key)
+		return nil
+	},
+	"HSET": func(s *Storage, a *recordArgs) error {
+		// a rewritten hash comes as one record with every field
+		key := a.string(0)
+		fields := map[string]any{}
+		for i := 1; i < len(a.args); i += 2 {
+			fields[a.string(i)] = a.value(i + 1)
+		}
+		if a.err != nil {
+			return a.err
+		}
+		for field, val := range fields {
+			if err := s.HSET(key, field, val); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	"FLUSHALL": func(s *Storage, a *recordArgs) error {
+		s.flushAll()
+		return nil
+	},
+	"HDEL": func(s *Storage, a *recordArgs) error {
+		key, fields := a.string(0), a.strings(1)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.HDEL(key, fields...)
+		return err
+	},
+	"LPUSH": func(s *Storage, a *recordArgs) error {
+		key, elems := a.string(0), a.values(1)
+		if a.err != nil {
+			return a.err
+		}
+		return s.LPUSH(key, elems)
+	},
+	"RPUSH": func(s *Storage, a *recordArgs) error {
+		key, elems := a.string(0), a.values(1)
+		if a.err != nil {
+			return a.err
+		}
+		return s.RPUSH(key, elems)
+	},
+	"RADDTOSET": func(s *Storage, a *recordArgs) error {
+		key, elems := a.string(0), a.values(1)
+		if a.err != nil {
+			return a.err
+		}
+		return s.RADDTOSET(key, elems)
+	},
+	"LPOP": func(s *Storage, a *recordArgs) error {
+		key, count := a.string(0), a.ints(1)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.LPOP(key, count...)
+		return err
+	},
+	"RPOP": func(s *Storage, a *recordArgs) error {
+		key, count := a.string(0), a.ints(1)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.RPOP(key, count...)
+		return err
+	},
+	"LSET": func(s *Storage, a *recordArgs) error {
+		key, index, elem := a.string(0), a.int(1), a.value(2)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.LSET(key, index, elem)
+		return err
+	},
+	"LINSERT": func(s *Storage, a *recordArgs) error {
+		key, where, pivot, elem := a.string(0), a.string(1), a.value(2), a.value(3)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.LINSERT(key, where, pivot, elem)
+		return err
+	},
+	"LREM": func(s *Storage, a *recordArgs) error {
+		key, count, elem := a.string(0), a.int(1), a.value(2)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.LREM(key, count, elem)
+		return err
+	},
+	"LTRIM": func(s *Storage, a *recordArgs) error {
+		key, start, stop := a.string(0), a.int(1), a.int(2)
+		if a.err != nil {
+			return a.err
+		}
+		return s.LTRIM(key, start, stop)
+	},
+	"LMOVE": func(s *Storage, a *recordArgs) error {
+		src, dst, from, to := a.string(0), a.string(1), a.string(2), a.string(3)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.LMove(src, dst, from, to)
+		return err
+	},
+	"SADD": func(s *Storage, a *recordArgs) error {
+		key, members := a.string(0), a.strings(1)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.SADD(key, members...)
+		return err
+	},
+	"SREM": func(s *Storage, a *recordArgs) error {
+		key, members := a.string(0), a.strings(1)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.SREM(key, members...)
+		return err
+	},
+	"SINTERSTORE": func(s *Storage, a *recordArgs) error {
+		return replaySetStore(a, s.SINTERSTORE)
+	},
+	"SUNIONSTORE": func(s *Storage, a *recordArgs) error {
+		return replaySetStore(a, s.SUNIONSTORE)
+	},
+	"SDIFFSTORE": func(s *Storage, a *recordArgs) error {
+		return replaySetStore(a, s.SDIFFSTORE)
+	},
+	"ZADD": func(s *Storage, a *recordArgs) error {
+		key := a.string(0)
+		var members []ZMember
+		for i := 1; i < len(a.args); i += 2 {
+			members = append(members, ZMember{Score: a.float(i), Member: a.string(i + 1)})
+		}
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.ZADD(key, members...)
+		return err
+	},
+	"ZREM": func(s *Storage, a *recordArgs) error {
+		key, members := a.string(0), a.strings(1)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.ZREM(key, members...)
+		return err
+	},
+	"ZREMRANGEBYRANK": func(s *Storage, a *recordArgs) error {
+		key, start, stop := a.string(0), a.int(1), a.int(2)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.ZREMRANGEBYRANK(key, start, stop)
+		return err
+	},
+	"ZREMRANGEBYSCORE": func(s *Storage, a *recordArgs) error {
+		key, min, max := a.string(0), a.string(1), a.string(2)
+		if a.err != nil {
+			return a.err
+		}
+		_, err := s.ZREMRANGEBYSCORE(key, min, max)
+		return err
+	},
+}
+
+func replaySetStore(a *recordArgs, store func(dst string, keys ...string) (int, error)) error {
+	dst, keys := a.string(0), a.strings(1)
+	if a.err != nil {
+		return a.err
+	}
+	_, err := store(dst, keys...)
+	return err
+}
+
+// recordArgs reads the arguments of a record. The first argument of the
+// wrong type or missing is kept in err and the reads after it return zeros.
+type recordArgs struct {
+	args []any
+	err  error
+}
+
+func (a *recordArgs) arg(i int) any {
+	if i >= len(a.args) {
+		a.fail(i)
+		return nil
+	}
+	return a.args[i]
+}
+
+func (a *recordArgs) fail(i int) {
+	if a.err == nil {
+		a.err = fmt.Errorf("bad argument %d", i)
+	}
+}
+
+func (a *recordArgs) value(i int) any {
+	return a.arg(i)
+}
+
+func (a *recordArgs) values(from int) []any {
+	if from >= len(a.args) {
+		a.fail(from)
+		return nil
+	}
+	return a.args[from:]
+}
+
+func (a *recordArgs) string(i int) string {
+	s, ok := a.arg(i).(string)
+	if !ok {
+		a.fail(i)
+	}
+	return s
+}
+
+func (a *recordArgs) strings(from int) []string {
+	res := []string{}
+	for i := from; i < len(a.args); i++ {
+		res = append(res, a.string(i))
+	}
+	return res
+}
+
+func (a *recordArgs) int(i int) int {
+	n, ok := a.arg(i).(int)
+	if !ok {
+		a.fail(i)
+	}
+	return n
+}
+
+func (a *recordArgs) int64(i int) int64 {
+	return int64(a.int(i))
+}
+
+func (a *recordArgs) ints(from int) []int {
+	res := []int{}
+	for i := from; i < len(a.args); i++ {
+		res = append(res, a.int(i))
+	}
+	return res
+}
+
+func (a *recordArgs) float(i int) float64 {
+	switch a.arg(i) {
+	case "+inf":
+		return math.Inf(1)
+	case "-inf":
+		return math.Inf(-1)
+	}
+
+	f, ok := number(a.arg(i))
+	if !ok {
+		a.fail(i)
+	}
+	return f
+}
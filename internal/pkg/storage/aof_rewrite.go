@@ -0,0 +1,320 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultRewritePercentage and defaultRewriteMinSize start a rewrite once
+	// the file has doubled since the last one and holds at least 64 MiB.
+	defaultRewritePercentage = 100
+	defaultRewriteMinSize    = 64 << 20
+
+	// rewriteCatchUpSize is how much of the changes made during a rewrite
+	// may be left to copy with the file locked, the rest is copied without.
+	rewriteCatchUpSize = 64 << 10
+)
+
+var (
+	// ErrAOFClosed is returned by RewriteAOF when the storage does not log to a file.
+	ErrAOFClosed = errors.New("append-only file not open")
+	// ErrRewriteInProgress is returned by RewriteAOF when a rewrite already runs.
+	ErrRewriteInProgress = errors.New("append-only file rewrite already in progress")
+
+	errRewriteAbandoned = errors.New("append-only file closed during rewrite")
+)
+
+// rewriteState tracks the rewrites of an append-only file. It is guarded by
+// the mutex of the file.
+type rewriteState struct {
+	// start runs a rewrite to the end, it is set by OpenAOF.
+	start   func()
+	running bool
+	// buf holds the records written since the rewrite took its records, which
+	// go to the end of the rewritten file.
+	buf  *bytes.Buffer
+	done chan struct{}
+
+	percentage int
+	minSize    int64
+
+	count   int
+	last    time.Time
+	lastErr error
+}
+
+// AOFStatus describes the append-only file of a storage.
+type AOFStatus struct {
+	Enabled          bool        `json:"enabled"`
+	Fsync            FsyncPolicy `json:"fsync,omitempty"`
+	Size             int64       `json:"size"`
+	BaseSize         int64       `json:"baseSize"`
+	Rewriting        bool        `json:"rewriting"`
+	Rewrites         int         `json:"rewrites"`
+	LastRewrite      time.Time   `json:"lastRewrite"`
+	LastRewriteError string      `json:"lastRewriteError,omitempty"`
+}
+
+func newAppendOnlyFile(logger *zap.Logger) *appendOnlyFile {
+	return &appendOnlyFile{
+		rewrites: rewriteState{
+			percentage: defaultRewritePercentage,
+			minSize:    defaultRewriteMinSize,
+		},
+		logger: logger,
+	}
+}
+
+// SetAOFAutoRewrite has the append-only file rewritten once it has grown by
+// percentage since the last rewrite, or since it was opened, and holds at
+// least minSize bytes. A percentage of 0 turns automatic rewrites off.
+func (s *Storage) SetAOFAutoRewrite(percentage int, minSize int64) {
+	a := s.aof
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.rewrites.percentage = max(percentage, 0)
+	a.rewrites.minSize = max(minSize, 0)
+}
+
+// RewriteAOF starts rewriting the append-only file in the background. The
+// new file holds the fewest records that build the keyspace as it is, then
+// the changes made while it was written, and replaces the old one at once.
+// Commands keep running and being logged to the old file meanwhile.
+func (s *Storage) RewriteAOF() error {
+	a := s.aof
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return ErrAOFClosed
+	}
+	if a.rewrites.running {
+		return ErrRewriteInProgress
+	}
+
+	a.beginRewrite()
+	return nil
+}
+
+// AOFStatus returns the state of the append-only file.
+func (s *Storage) AOFStatus() AOFStatus {
+	a := s.aof
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	status := AOFStatus{
+		Enabled:     a.file != nil,
+		Size:        a.size,
+		BaseSize:    a.baseSize,
+		Rewriting:   a.rewrites.running,
+		Rewrites:    a.rewrites.count,
+		LastRewrite: a.rewrites.last,
+	}
+	if status.Enabled {
+		status.Fsync = a.policy
+	}
+	if a.rewrites.lastErr != nil {
+		status.LastRewriteError = a.rewrites.lastErr.Error()
+	}
+
+	return status
+}
+
+// beginRewrite must be called with a.mu held.
+func (a *appendOnlyFile) beginRewrite() {
+	a.rewrites.running = true
+	a.rewrites.done = make(chan struct{})
+	go a.rewrites.start()
+}
+
+// rewriteIfGrown starts a rewrite if the file has grown past the thresholds.
+// It must be called with a.mu held.
+func (a *appendOnlyFile) rewriteIfGrown() {
+	r := &a.rewrites
+	if r.running || r.percentage == 0 || a.size < r.minSize {
+		return
+	}
+	if a.size < a.baseSize+a.baseSize*int64(r.percentage)/100 {
+		return
+	}
+
+	a.logger.Info("append-only file grew, rewriting it",
+		zap.Int64("size", a.size),
+		zap.Int64("base_size", a.baseSize))
+	a.beginRewrite()
+}
+
+// rewriteAOF writes the rewritten file next to the old one and renames it
+// over it.
+func (s *Storage) rewriteAOF() {
+	a := s.aof
+	began := time.Now()
+
+	a.mu.Lock()
+	file, tmpPath := a.file, a.path+".rewrite"
+	a.mu.Unlock()
+
+	var tmp *os.File
+	err := errRewriteAbandoned
+	// the file may have been closed before the rewrite got to run
+	if file != nil {
+		tmp, err = os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0644)
+	}
+	if err == nil {
+		err = s.writeRewrite(file, tmp)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err == nil && a.file != file {
+		err = errRewriteAbandoned
+	}
+	if err == nil {
+		err = a.switchTo(tmp, tmpPath)
+	}
+	if err != nil && tmp != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+	}
+
+	r := &a.rewrites
+	r.running = false
+	r.buf = nil
+	close(r.done)
+	r.done = nil
+
+	switch {
+	case errors.Is(err, errRewriteAbandoned):
+		a.logger.Info("append-only file rewrite abandoned")
+	case err != nil:
+		r.lastErr = err
+		a.logger.Error("append-only file rewrite failed", zap.Error(err))
+	default:
+		r.count++
+		r.last = time.Now()
+		r.lastErr = nil
+		a.logger.Info("append-only file rewritten",
+			zap.Int64("size", a.size),
+			zap.Duration("took", time.Since(began)))
+	}
+}
+
+// writeRewrite writes the records of the keyspace to tmp as it reads them from
+// a view, one key at a time, then the changes logged to file since, until few
+// enough are left for switchTo.
+func (s *Storage) writeRewrite(file, tmp *os.File) error {
+	a := s.aof
+
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	// the buffer and the view start with every shard locked, so the view is
+	// the keyspace at one instant and every change after it goes to the
+	// buffer as well
+	unlock := s.lockAll()
+	a.mu.Lock()
+	if a.file != file {
+		a.mu.Unlock()
+		unlock()
+		return errRewriteAbandoned
+	}
+	a.rewrites.buf = new(bytes.Buffer)
+	a.mu.Unlock()
+	s.markView()
+	unlock()
+
+	w := bufio.NewWriter(tmp)
+	err := s.readView(func(k *snapshotKey) error {
+		for _, rec := range k.records() {
+			data, err := json.Marshal(rec)
+			if err != nil {
+				return fmt.Errorf("failed to marshal record: %w", err)
+			}
+			w.Write(data)
+			w.WriteByte('\n')
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write rewritten file: %w", err)
+	}
+
+	for {
+		a.mu.Lock()
+		pending := a.rewrites.buf
+		if a.file != file || pending.Len() <= rewriteCatchUpSize {
+			a.mu.Unlock()
+			break
+		}
+		a.rewrites.buf = new(bytes.Buffer)
+		a.mu.Unlock()
+
+		if _, err := tmp.Write(pending.Bytes()); err != nil {
+			return fmt.Errorf("failed to write rewritten file: %w", err)
+		}
+	}
+
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync rewritten file: %w", err)
+	}
+	return nil
+}
+
+// switchTo appends the last changes to tmp and puts it in place of the file.
+// It must be called with a.mu held.
+func (a *appendOnlyFile) switchTo(tmp *os.File, tmpPath string) error {
+	if a.rewrites.buf.Len() > 0 {
+		if _, err := tmp.Write(a.rewrites.buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to write rewritten file: %w", err)
+		}
+		if err := tmp.Sync(); err != nil {
+			return fmt.Errorf("failed to sync rewritten file: %w", err)
+		}
+	}
+
+	stat, err := tmp.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat rewritten file: %w", err)
+	}
+	if err := os.Rename(tmpPath, a.path); err != nil {
+		return fmt.Errorf("failed to replace append-only file: %w", err)
+	}
+	syncDir(filepath.Dir(a.path), a.logger)
+
+	if err := a.file.Close(); err != nil {
+		a.logger.Warn("old append-only file not closed", zap.Error(err))
+	}
+	a.file = tmp
+	a.size = stat.Size()
+	a.baseSize = stat.Size()
+	a.dirty = false
+
+	return nil
+}
+
+// syncDir syncs a directory so that a rename in it survives a crash. Some
+// systems cannot sync directories, which is only logged.
+func syncDir(dir string, logger *zap.Logger) {
+	d, err := os.Open(dir)
+	if err == nil {
+		err = d.Sync()
+		d.Close()
+	}
+	if err != nil {
+		logger.Warn("directory not synced", zap.String("dir", dir), zap.Error(err))
+	}
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitRewrite(t *testing.T, s *Storage) AOFStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := s.AOFStatus()
+		if !status.Rewriting {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("rewrite did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAOFRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	s := openAOF(t, path, FsyncNo)
+	s.SetAOFAutoRewrite(0, 0)
+
+	for i := 0; i < 1000; i++ {
+		s.RPUSH("queue", []any{i, i + 1})
+		s.LPOP("queue")
+		s.Incr("counter")
+	}
+	s.HSET("user:1", "name", "ann")
+	s.SetWithTTL("session", 1, time.Hour)
+
+	before := s.AOFStatus().Size
+	if err := s.RewriteAOF(); err != nil {
+		t.Fatalf("RewriteAOF: %v", err)
+	}
+
+	// commands keep being logged while the file is rewritten
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				s.Incr("counter")
+				s.RPUSH("during", []any{i})
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := waitRewrite(t, s)
+	if status.Rewrites != 1 || status.LastRewriteError != "" {
+		t.Errorf("status: got %+v", status)
+	}
+
+	s.Incr("counter")
+	if err := s.RewriteAOF(); err != nil {
+		t.Fatalf("second RewriteAOF: %v", err)
+	}
+	status = waitRewrite(t, s)
+	if status.Size >= before || status.BaseSize != status.Size {
+		t.Errorf("rewritten file: got %d bytes, was %d", status.Size, before)
+	}
+
+	s.SADD("after", "x")
+	want := dump(t, s)
+	s.CloseAOF()
+
+	replayed := openAOF(t, path, FsyncNo)
+	if got := dump(t, replayed); got != want {
+		t.Errorf("replayed storage differs\n got: %s\nwant: %s", got, want)
+	}
+	if n, _ := replayed.LLEN("during"); n != 1000 {
+		t.Errorf("LLEN during: got %v", n)
+	}
+}
+
+func TestAOFRewriteDuringRemovals(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	s := openAOF(t, path, FsyncNo)
+	s.SetAOFAutoRewrite(0, 0)
+
+	for i := 0; i < 2000; i++ {
+		s.Set(strconv.Itoa(i), i)
+	}
+	if err := s.RewriteAOF(); err != nil {
+		t.Fatalf("RewriteAOF: %v", err)
+	}
+
+	// keys the rewrite has not read yet are removed, changed and added
+	for i := 0; i < 2000; i += 2 {
+		s.Del(strconv.Itoa(i))
+		s.Set(strconv.Itoa(i+1), "changed")
+		s.Set(strconv.Itoa(i)+":new", i)
+	}
+
+	if status := waitRewrite(t, s); status.LastRewriteError != "" {
+		t.Fatalf("rewrite failed: %s", status.LastRewriteError)
+	}
+	want := dump(t, s)
+	s.CloseAOF()
+
+	replayed := openAOF(t, path, FsyncNo)
+	if got := dump(t, replayed); got != want {
+		t.Errorf("replayed storage differs from the one rewritten")
+	}
+}
+
+func TestAOFAutoRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	s := openAOF(t, path, FsyncNo)
+	s.SetAOFAutoRewrite(100, 4096)
+
+	for i := 0; i < 1000; i++ {
+		s.Set("key", i)
+	}
+
+	status := waitRewrite(t, s)
+	if status.Rewrites == 0 {
+		t.Fatalf("file of %d bytes was not rewritten", status.Size)
+	}
+
+	s.CloseAOF()
+	replayed := openAOF(t, path, FsyncNo)
+	if got := replayed.Get("key"); got == nil || *got != 999 {
+		t.Errorf("Get key: got %v", got)
+	}
+}
+
+func TestAOFRewriteErrors(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+	if err := s.RewriteAOF(); !errors.Is(err, ErrAOFClosed) {
+		t.Errorf("RewriteAOF without a file: got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	if err := s.OpenAOF(path, FsyncNo); err != nil {
+		t.Fatalf("OpenAOF: %v", err)
+	}
+	s.Set("key", 1)
+
+	// closing during a rewrite abandons it
+	s.RewriteAOF()
+	if err := s.CloseAOF(); err != nil {
+		t.Errorf("CloseAOF: %v", err)
+	}
+	if status := s.AOFStatus(); status.Enabled || status.Rewriting {
+		t.Errorf("status after close: got %+v", status)
+	}
+}
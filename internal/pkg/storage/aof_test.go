@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// dump describes every key with the records that build it, as JSON so that
+// whole numbers compare equal whatever their Go type.
+func dump(t *testing.T, s *Storage) string {
+	t.Helper()
+	defer s.rlockAll()()
+
+	keys := map[string][][]any{}
+	for _, sh := range s.shards {
+		sh.eachKey(func(key string, _ KeyType) {
+			keys[key] = sh.keyRecords(key)
+		})
+	}
+
+	data, err := json.Marshal(keys)
+	if err != nil {
+		t.Fatalf("dump: %v", err)
+	}
+	return string(data)
+}
+
+func openAOF(t *testing.T, path string, policy FsyncPolicy) *Storage {
+	t.Helper()
+
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+	if err := s.OpenAOF(path, policy); err != nil {
+		t.Fatalf("OpenAOF: %v", err)
+	}
+	t.Cleanup(func() { s.CloseAOF() })
+
+	return &s
+}
+
+func TestAOFReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	s := openAOF(t, path, FsyncAlways)
+
+	s.Set("name", "kv")
+	s.SetWithTTL("session", map[string]any{"user": 1}, time.Hour)
+	s.SetWithOptions("session", "renewed", SetOptions{XX: true, KeepTTL: true})
+	s.IncrBy("views", 41)
+	s.Incr("views")
+	s.IncrByFloat("ratio", 0.5)
+	s.Set("gone", 1)
+	s.Del("gone")
+	s.Set("short", 1)
+	s.ExpireAt("short", time.Now().Add(-time.Second))
+
+	s.HSET("user:1", "name", "ann")
+	s.HSET("user:1", "age", 30)
+	s.HINCRBY("user:1", "age", 1)
+	s.HSET("user:1", "tmp", true)
+	s.HDEL("user:1", "tmp")
+
+	s.RPUSH("queue", []any{"a", "b", "c", "d"})
+	s.LPUSH("queue", []any{"z"})
+	s.LPOP("queue")
+	s.LSET("queue", 0, "A")
+	s.LINSERT("queue", "AFTER", "b", "b2")
+	s.LREM("queue", 1, "c")
+	s.LMove("queue", "done", "RIGHT", "LEFT")
+	s.LTRIM("queue", 0, 1)
+
+	s.SADD("tags", "x", "y", "z")
+	s.SREM("tags", "y")
+	s.SPOP("tags", 1)
+	s.SADD("other", "x", "q")
+	s.SUNIONSTORE("all", "tags", "other")
+
+	s.ZADD("board", ZMember{"ann", 10}, ZMember{"bob", math.Inf(1)}, ZMember{"cid", 3})
+	s.ZINCRBY("board", 5, "ann")
+	s.ZREMRANGEBYSCORE("board", "-inf", "4")
+
+	s.JSONSet("doc", "$", map[string]any{"n": 1, "list": []any{}})
+	s.JSONNumIncrBy("doc", "$.n", 2)
+	s.JSONArrAppend("doc", "$.list", "item")
+
+	s.Set("counter", 1)
+	s.Expire("counter", time.Hour)
+	s.Persist("counter")
+	s.GetDel("name")
+	s.MSet(map[string]any{"m1": 1, "m2": "two"})
+	s.CompareAndSwap("m1", 1, 10)
+
+	s.Update(func(tx *Tx) error {
+		tx.Set("tx:a", 1)
+		tx.RPUSH("tx:list", []any{1})
+		return nil
+	})
+	s.Update(func(tx *Tx) error {
+		tx.Set("tx:rolled", 1)
+		return errors.New("abort")
+	})
+
+	popped := make(chan struct{})
+	go func() {
+		s.BLPop(context.Background(), []string{"jobs"}, time.Second)
+		close(popped)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	s.RPUSH("jobs", []any{"job1", "job2"})
+	<-popped
+
+	want := dump(t, s)
+	if err := s.CloseAOF(); err != nil {
+		t.Fatalf("CloseAOF: %v", err)
+	}
+
+	replayed := openAOF(t, path, FsyncNo)
+	if got := dump(t, replayed); got != want {
+		t.Errorf("replayed storage differs\n got: %s\nwant: %s", got, want)
+	}
+	if replayed.Exists("tx:rolled") != 0 {
+		t.Errorf("rolled back transaction was replayed")
+	}
+}
+
+func TestAOFTruncatedRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	s := openAOF(t, path, FsyncEverySec)
+	s.Set("kept", 1)
+	s.CloseAOF()
+
+	stat, _ := os.Stat(path)
+	good := stat.Size()
+
+	for _, tail := range []string{
+		`["SET","lost"`,
+		"[\"MULTI\"]\n[\"SET\",\"lost\",1,0]\n",
+		"{garbage\n",
+	} {
+		f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		f.WriteString(tail)
+		f.Close()
+
+		replayed := openAOF(t, path, FsyncNo)
+		if replayed.Get("kept") == nil || replayed.Exists("lost") != 0 {
+			t.Errorf("tail %q: replay kept %v, lost exists %d", tail, replayed.Get("kept"), replayed.Exists("lost"))
+		}
+		replayed.CloseAOF()
+
+		if stat, _ := os.Stat(path); stat.Size() != good {
+			t.Errorf("tail %q: file is %d bytes, want %d", tail, stat.Size(), good)
+		}
+	}
+}
+
+func TestAOFCorruptRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	os.WriteFile(path, []byte("[\"SET\",\"a\",1,0]\n{garbage\n[\"SET\",\"b\",1,0]\n"), 0644)
+
+	s, _ := NewStorage()
+	if err := s.OpenAOF(path, FsyncNo); err == nil {
+		t.Errorf("a damaged record in the middle of the file was skipped")
+	}
+
+	os.WriteFile(path, []byte("[\"NOPE\",\"a\"]\n[\"SET\",\"b\",1,0]\n"), 0644)
+	if err := s.OpenAOF(path, FsyncNo); err == nil {
+		t.Errorf("an unknown command was replayed")
+	}
+
+	if err := s.OpenAOF(path, "sometimes"); err == nil {
+		t.Errorf("an unknown fsync policy was accepted")
+	}
+}
+
+func TestAOFLoadFromFile(t *testing.T) {
+	dir := t.TempDir()
+
+	src, _ := NewStorage()
+	src.Set("from-file", "yes")
+	src.RPUSH("list", []any{1, 2})
+	if err := src.SaveToFile(filepath.Join(dir, "dump.json")); err != nil {
+		t.Fatalf("SaveToFile: %v", err)
+	}
+
+	path := filepath.Join(dir, "appendonly.aof")
+	s := openAOF(t, path, FsyncAlways)
+	s.Set("before", 1)
+	s.SADD("set", "kept")
+	if err := s.LoadFromFile(filepath.Join(dir, "dump.json")); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	s.Set("after", 1)
+	s.RPUSH("list", []any{3})
+	opts := LoadOptions{Mode: LoadMergeOverwrite, Match: "l*"}
+	if _, err := s.LoadFromFileWithOptions(filepath.Join(dir, "dump.json"), opts); err != nil {
+		t.Fatalf("LoadFromFileWithOptions: %v", err)
+	}
+	want := dump(t, s)
+	s.CloseAOF()
+
+	replayed := openAOF(t, path, FsyncNo)
+	if got := dump(t, replayed); got != want {
+		t.Errorf("replayed storage differs\n got: %s\nwant: %s", got, want)
+	}
+}
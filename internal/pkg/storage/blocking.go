@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PoppedElem is an element handed out by a blocking pop together with the
+// key of the list it came from.
+type PoppedElem struct {
+	Key   string
+	Value any
+}
+
+// waiter is a client blocked on one or more lists. Waiters are queued per key
+// in arrival order and each is served at most once. The queues are guarded by
+// s.waitMu, which is always taken after the shard locks.
+type waiter struct {
+	keys   []string
+	dst    string
+	pop    func(key string) (any, error)
+	result chan waiterResult
+	served bool
+}
+
+type waiterResult struct {
+	elem PoppedElem
+	err  error
+}
+
+// BLPop pops the head of the first non-empty list among keys, waiting up to
+// timeout for one of them to receive an element. A zero timeout waits until ctx
+// is done. It returns nil if the timeout expires and ctx.Err() if ctx is done first.
+func (s *Storage) BLPop(ctx context.Context, keys []string, timeout time.Duration) (*PoppedElem, error) {
+	return s.blockingPop(ctx, keys, "", timeout, func(key string) (any, error) {
+		s.propagate("LPOP", key)
+		return s.shard(key).popElem(key, true), nil
+	})
+}
+
+// BRPop is BLPop popping from the tail of the lists.
+func (s *Storage) BRPop(ctx context.Context, keys []string, timeout time.Duration) (*PoppedElem, error) {
+	return s.blockingPop(ctx, keys, "", timeout, func(key string) (any, error) {
+		s.propagate("RPOP", key)
+		return s.shard(key).popElem(key, false), nil
+	})
+}
+
+// blockingPop runs pop on the first non-empty list among keys, or waits for
+// one. A non-empty dst is a list pop pushes to, it is locked along with the
+// popped key and must hold a list too.
+func (s *Storage) blockingPop(ctx context.Context, keys []string, dst string, timeout time.Duration, pop func(key string) (any, error)) (*PoppedElem, error) {
+	if len(keys) == 0 || timeout < 0 {
+		return nil, errors.New("WrongArgs")
+	}
+
+	locked := keys
+	if dst != "" {
+		locked = append(append([]string{}, keys...), dst)
+	}
+	unlock := s.lockKeys(locked...)
+
+	for _, key := range locked {
+		if err := s.shard(key).checkType(key, TypeList); err != nil {
+			unlock()
+			return nil, err
+		}
+	}
+
+	for _, key := range keys {
+		if list, ok := s.shard(key).list[key]; ok && len(list.Elem) > 0 {
+			val, err := pop(key)
+			unlock()
+			if err != nil {
+				return nil, err
+			}
+			if dst != "" {
+				s.serveWaiters(dst)
+			}
+			return &PoppedElem{Key: key, Value: val}, nil
+		}
+	}
+
+	// a transaction holds every shard, so it cannot wait for a push
+	if s.tx != nil {
+		unlock()
+		return nil, nil
+	}
+
+	w := &waiter{
+		keys:   keys,
+		dst:    dst,
+		pop:    pop,
+		result: make(chan waiterResult, 1),
+	}
+	s.waitMu.Lock()
+	for _, key := range keys {
+		s.waiters[key] = append(s.waiters[key], w)
+	}
+	s.waitMu.Unlock()
+	unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case res := <-w.result:
+		return res.elemOrErr()
+	case <-expired:
+	case <-ctx.Done():
+	}
+
+	s.waitMu.Lock()
+	// the element may have been handed over while we were giving up
+	if w.served {
+		s.waitMu.Unlock()
+		res := <-w.result
+		return res.elemOrErr()
+	}
+	s.removeWaiter(w)
+	s.waitMu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// serveWaiters hands elements of the list at key to blocked clients in the
+// order they started waiting. It must be called without any shard locked
+// after every operation that adds elements to a list. Inside a transaction the
+// clients are served once it commits.
+func (s *Storage) serveWaiters(key string) {
+	if s.tx != nil {
+		s.tx.pushed = append(s.tx.pushed, key)
+		return
+	}
+
+	for {
+		s.waitMu.Lock()
+		queue := s.waiters[key]
+		if len(queue) == 0 {
+			s.waitMu.Unlock()
+			return
+		}
+		w := queue[0]
+		s.waitMu.Unlock()
+
+		if !s.serveWaiter(key, w) {
+			return
+		}
+	}
+}
+
+// serveWaiter pops an element of key for w under the locks its pop needs. It
+// reports false once the list is empty.
+func (s *Storage) serveWaiter(key string, w *waiter) bool {
+	locked := []string{key}
+	if w.dst != "" {
+		locked = append(locked, w.dst)
+	}
+	unlock := s.lockKeys(locked...)
+
+	s.waitMu.Lock()
+	if queue := s.waiters[key]; len(queue) == 0 || queue[0] != w {
+		// w gave up or was served from another key in the meantime
+		s.waitMu.Unlock()
+		unlock()
+		return true
+	}
+
+	if list, ok := s.shard(key).list[key]; !ok || len(list.Elem) == 0 {
+		s.waitMu.Unlock()
+		unlock()
+		return false
+	}
+
+	s.removeWaiter(w)
+	w.served = true
+	s.waitMu.Unlock()
+
+	val, err := w.pop(key)
+	unlock()
+	w.result <- waiterResult{elem: PoppedElem{Key: key, Value: val}, err: err}
+	s.logger.Info("blocked client served", zap.String("key", key))
+
+	if w.dst != "" && err == nil {
+		s.serveWaiters(w.dst)
+	}
+	return true
+}
+
+func (r waiterResult) elemOrErr() (*PoppedElem, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return &r.elem, nil
+}
+
+// removeWaiter must be called with s.waitMu held.
+func (s *Storage) removeWaiter(w *waiter) {
+	for _, key := range w.keys {
+		queue := s.waiters[key]
+		for i, other := range queue {
+			if other == w {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+
+		if len(queue) == 0 {
+			delete(s.waiters, key)
+		} else {
+			s.waiters[key] = queue
+		}
+	}
+}
+
+// popElem removes one element from the head or the tail of a non-empty list.
+// It must be called with sh.mu held.
+func (sh *shard) popElem(key string, left bool) any {
+	list := sh.list[key]
+
+	var val any
+	if left {
+		val = list.Elem[0]
+		list.Elem = list.Elem[1:]
+	} else {
+		last := len(list.Elem) - 1
+		val = list.Elem[last]
+		list.Elem = list.Elem[:last]
+	}
+
+	sh.removeIfEmpty(key)
+	sh.bumpVersion(key)
+	return val
+}
+
+// pushElem adds one element to the head or the tail of a list, creating it if
+// needed. It must be called with sh.mu held.
+func (sh *shard) pushElem(key string, val any, left bool) {
+	list, ok := sh.list[key]
+	if !ok {
+		list = &List{}
+		sh.list[key] = list
+	}
+
+	if left {
+		list.Elem = append([]any{val}, list.Elem...)
+	} else {
+		list.Elem = append(list.Elem, val)
+	}
+	sh.bumpVersion(key)
+}
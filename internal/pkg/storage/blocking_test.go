@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBLPopImmediate(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.RPUSH("second", []any{"a", "b"})
+
+	res, err := s.BLPop(context.Background(), []string{"first", "second"}, time.Second)
+	if err != nil || res == nil || res.Key != "second" || res.Value != "a" {
+		t.Errorf("BLPop: got %+v, %v", res, err)
+	}
+
+	res, err = s.BRPop(context.Background(), []string{"second"}, time.Second)
+	if err != nil || res == nil || res.Value != "b" {
+		t.Errorf("BRPop: got %+v, %v", res, err)
+	}
+
+	if s.Exists("second") != 0 {
+		t.Errorf("drained list still exists")
+	}
+}
+
+func TestBLPopWaitsForPush(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	results := []chan *PoppedElem{make(chan *PoppedElem, 1), make(chan *PoppedElem, 1)}
+	for _, ch := range results {
+		go func(ch chan *PoppedElem) {
+			res, _ := s.BLPop(context.Background(), []string{"queue"}, time.Second)
+			ch <- res
+		}(ch)
+		// make sure the waiters queue up in a known order
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	s.RPUSH("queue", []any{"job1", "job2"})
+
+	for i, expected := range []string{"job1", "job2"} {
+		res := <-results[i]
+		if res == nil || res.Value != expected {
+			t.Errorf("waiters served out of order: got %+v, want %s", res, expected)
+		}
+	}
+}
+
+func TestBLPopTimeout(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	start := time.Now()
+	res, err := s.BLPop(context.Background(), []string{"queue"}, 30*time.Millisecond)
+	if res != nil || err != nil {
+		t.Errorf("BLPop timeout: got %+v, %v", res, err)
+	}
+	if time.Since(start) < 30*time.Millisecond {
+		t.Errorf("BLPop returned before the timeout")
+	}
+
+	s.RPUSH("queue", []any{"job"})
+	if n, _ := s.LLEN("queue"); n != 1 {
+		t.Errorf("timed out waiter took an element")
+	}
+}
+
+func TestBLPopCancel(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	res, err := s.BRPop(ctx, []string{"queue"}, 0)
+	if res != nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("BRPop cancel: got %+v, %v", res, err)
+	}
+
+	s.Set("scalar", "value")
+	if _, err := s.BLPop(context.Background(), []string{"scalar"}, time.Second); !errors.Is(err, ErrWrongType) {
+		t.Errorf("BLPop on scalar: got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// MGet returns the values stored at keys in the same order, nil for a key
+// that does not exist or does not hold a scalar. All keys are read at once,
+// so the values are never a mix of before and after another command.
+func (s *Storage) MGet(keys ...string) []*any {
+	defer s.rlockKeys(keys...)()
+
+	res := make([]*any, len(keys))
+	for i, key := range keys {
+		if val, ok := s.shard(key).inner[key]; ok {
+			res[i] = &val.Val
+		}
+	}
+
+	return res
+}
+
+// MSet stores every value of values at its key, like Set does, as one
+// command. Nothing is stored if a value has no kind or a key holds another
+// type.
+func (s *Storage) MSet(values map[string]any) error {
+	if err := s.checkMemory(); err != nil {
+		return err
+	}
+
+	vals, keys, err := newValues(values)
+	if err != nil {
+		return err
+	}
+
+	defer s.lockKeys(keys...)()
+
+	for _, key := range keys {
+		if err := s.shard(key).checkType(key, TypeString); err != nil {
+			return err
+		}
+	}
+	s.setValues(vals)
+
+	s.logger.Info("MSET executed", zap.Int("keys", len(keys)))
+	return nil
+}
+
+// MSetNX is MSet done only if none of the keys exist, whatever their type.
+// It reports whether the values were stored.
+func (s *Storage) MSetNX(values map[string]any) (bool, error) {
+	if err := s.checkMemory(); err != nil {
+		return false, err
+	}
+
+	vals, keys, err := newValues(values)
+	if err != nil {
+		return false, err
+	}
+
+	defer s.lockKeys(keys...)()
+
+	for _, key := range keys {
+		if s.shard(key).exists(key) {
+			return false, nil
+		}
+	}
+	s.setValues(vals)
+
+	s.logger.Info("MSETNX executed", zap.Int("keys", len(keys)))
+	return true, nil
+}
+
+// MPush appends the elements of lists to the tails of their lists, like RPUSH
+// does, as one command. Nothing is pushed if a list is given no elements or a
+// key holds another type.
+func (s *Storage) MPush(lists map[string][]any) error {
+	if err := s.checkMemory(); err != nil {
+		return err
+	}
+	if len(lists) == 0 {
+		return errors.New("WrongArgs")
+	}
+
+	keys := make([]string, 0, len(lists))
+	for key, elements := range lists {
+		if len(elements) == 0 {
+			return errors.New("WrongArgs")
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		defer s.serveWaiters(key)
+	}
+	defer s.lockKeys(keys...)()
+
+	for _, key := range keys {
+		if err := s.shard(key).checkType(key, TypeList); err != nil {
+			return err
+		}
+	}
+
+	records := make([][]any, 0, len(keys))
+	for _, key := range keys {
+		sh := s.shard(key)
+		list, ok := sh.list[key]
+		if !ok {
+			list = &List{}
+			sh.list[key] = list
+		}
+		list.Elem = append(list.Elem, lists[key]...)
+		sh.bumpVersion(key)
+		records = append(records, append([]any{"RPUSH", key}, lists[key]...))
+	}
+	s.propagateAll(records)
+
+	s.logger.Info("MPUSH executed", zap.Int("keys", len(keys)))
+	return nil
+}
+
+func newValues(values map[string]any) (map[string]Value, []string, error) {
+	if len(values) == 0 {
+		return nil, nil, errors.New("WrongArgs")
+	}
+
+	vals := make(map[string]Value, len(values))
+	keys := make([]string, 0, len(values))
+	for key, value := range values {
+		val, err := newValue(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		vals[key] = val
+		keys = append(keys, key)
+	}
+
+	return vals, keys, nil
+}
+
+// setValues must be called with the shards of every key locked. The keys
+// are logged together, so that replay restores all of them or none.
+func (s *Storage) setValues(vals map[string]Value) {
+	keys := make([]string, 0, len(vals))
+	for key, val := range vals {
+		sh := s.shard(key)
+		sh.inner[key] = val
+		delete(sh.innerExpire, key)
+		sh.bumpVersion(key)
+		keys = append(keys, key)
+	}
+	s.propagateKeys(keys)
+}
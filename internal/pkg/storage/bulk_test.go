@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMGet(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.Set("a", 1)
+	s.Set("b", "two")
+	s.RPUSH("list", []any{3})
+
+	res := s.MGet("a", "missing", "b", "list", "a")
+	if len(res) != 5 {
+		t.Fatalf("MGet returned %d values", len(res))
+	}
+	want := []any{1, nil, "two", nil, 1}
+	for i, val := range res {
+		if (val == nil) != (want[i] == nil) || (val != nil && *val != want[i]) {
+			t.Errorf("MGet[%d]: got %v, want %v", i, val, want[i])
+		}
+	}
+}
+
+func TestMSet(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.SetWithTTL("a", 0, time.Hour)
+	if err := s.MSet(map[string]any{"a": 1, "b": "two", "c": nil}); err != nil {
+		t.Fatalf("MSet: %v", err)
+	}
+	if val := s.Get("b"); val == nil || *val != "two" {
+		t.Errorf("b: got %v", val)
+	}
+	if ttl := s.TTL("a"); ttl != TTLNoExpire {
+		t.Errorf("MSet kept the deadline of a: %v", ttl)
+	}
+
+	// a key of the wrong type fails the whole command
+	s.SADD("set", "x")
+	err = s.MSet(map[string]any{"a": 10, "set": 1})
+	if !errors.Is(err, ErrWrongType) {
+		t.Errorf("MSet over a set: got %v", err)
+	}
+	if val := s.Get("a"); *val != 1 {
+		t.Errorf("failed MSet changed a to %v", *val)
+	}
+
+	if err := s.MSet(map[string]any{"a": 10, "bad": []int{1}}); err == nil {
+		t.Errorf("MSet with a value without a kind succeeded")
+	}
+	if err := s.MSet(nil); err == nil {
+		t.Errorf("MSet without values succeeded")
+	}
+}
+
+func TestMSetNX(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	if ok, err := s.MSetNX(map[string]any{"a": 1, "b": 2}); err != nil || !ok {
+		t.Fatalf("MSetNX on missing keys: got %v, %v", ok, err)
+	}
+
+	if ok, err := s.MSetNX(map[string]any{"b": 20, "c": 30}); err != nil || ok {
+		t.Errorf("MSetNX with an existing key: got %v, %v", ok, err)
+	}
+	if s.Exists("c") != 0 {
+		t.Errorf("MSetNX stored c although b exists")
+	}
+
+	s.HSET("hash", "f", 1)
+	if ok, _ := s.MSetNX(map[string]any{"hash": 1, "d": 4}); ok {
+		t.Errorf("MSetNX over a hash stored the values")
+	}
+}
+
+func TestMSetReplayAllOrNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	s := openAOF(t, path, FsyncAlways)
+	s.Set("before", 1)
+	stat, _ := os.Stat(path)
+	good := stat.Size()
+
+	s.MSet(map[string]any{"a": 1, "b": 2, "c": 3})
+	s.CloseAOF()
+
+	data, _ := os.ReadFile(path)
+	lines := bytes.SplitAfter(data[good:], []byte("\n"))
+	if len(lines) < 4 || !bytes.Contains(lines[0], []byte("MULTI")) {
+		t.Fatalf("MSet was not logged as one transaction: %q", data[good:])
+	}
+	// Cut the file after the second record inside the transaction.
+	cut := good + int64(len(lines[0])+len(lines[1])+len(lines[2]))
+	if err := os.Truncate(path, cut); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+
+	replayed := openAOF(t, path, FsyncNo)
+	if replayed.Get("before") == nil {
+		t.Errorf("replay lost the key written before MSet")
+	}
+	if n := replayed.Exists("a", "b", "c"); n != 0 {
+		t.Errorf("replay restored %d keys of a partial MSet", n)
+	}
+}
+
+func TestMPush(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.RPUSH("a", []any{1})
+	if err := s.MPush(map[string][]any{"a": {2, 3}, "b": {"x"}}); err != nil {
+		t.Fatalf("MPush: %v", err)
+	}
+	if got, _ := s.LRANGE("a", 0, -1); !reflect.DeepEqual(got, []any{1, 2, 3}) {
+		t.Errorf("a: got %v", got)
+	}
+	if got, _ := s.LRANGE("b", 0, -1); !reflect.DeepEqual(got, []any{"x"}) {
+		t.Errorf("b: got %v", got)
+	}
+
+	s.Set("scalar", 1)
+	if err := s.MPush(map[string][]any{"c": {1}, "scalar": {1}}); !errors.Is(err, ErrWrongType) {
+		t.Errorf("MPush onto a scalar: got %v", err)
+	}
+	if err := s.MPush(map[string][]any{"c": {1}, "d": {}}); err == nil {
+		t.Errorf("MPush of an empty list succeeded")
+	}
+	if s.Exists("c") != 0 {
+		t.Errorf("failed MPush pushed to c")
+	}
+
+	popped := make(chan *PoppedElem)
+	go func() {
+		res, _ := s.BLPop(context.Background(), []string{"waiting"}, time.Second)
+		popped <- res
+	}()
+	time.Sleep(20 * time.Millisecond)
+	s.MPush(map[string][]any{"waiting": {"job"}})
+	if res := <-popped; res == nil || res.Value != "job" {
+		t.Errorf("blocked client got %v", res)
+	}
+}
+
+func TestMPushReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	s := openAOF(t, path, FsyncAlways)
+	s.MPush(map[string][]any{"a": {1, 2}, "b": {"x"}})
+	want := dump(t, s)
+	s.CloseAOF()
+
+	replayed := openAOF(t, path, FsyncNo)
+	if got := dump(t, replayed); got != want {
+		t.Errorf("replayed storage differs\n got: %s\nwant: %s", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"errors"
+	"math"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrNotFloat is returned when float arithmetic is asked of a value that is not a number.
+	ErrNotFloat = errors.New("value is not a valid float")
+	// ErrOverflow is returned when an increment would not fit in an int.
+	ErrOverflow = errors.New("increment or decrement would overflow")
+)
+
+// Incr adds one to the integer stored at key.
+func (s *Storage) Incr(key string) (int, error) {
+	return s.IncrBy(key, 1)
+}
+
+// Decr subtracts one from the integer stored at key.
+func (s *Storage) Decr(key string) (int, error) {
+	return s.IncrBy(key, -1)
+}
+
+// IncrBy adds delta to the integer stored at key, creating it at zero if it
+// does not exist, and returns the new value. The key keeps its deadline.
+func (s *Storage) IncrBy(key string, delta int) (int, error) {
+	if err := s.checkMemory(); err != nil {
+		return 0, err
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return 0, err
+	}
+
+	current := 0
+	if val, ok := sh.inner[key]; ok {
+		n, ok := toInt(val)
+		if !ok {
+			return 0, ErrNotInteger
+		}
+		current = n
+	}
+
+	if (delta > 0 && current > math.MaxInt-delta) || (delta < 0 && current < math.MinInt-delta) {
+		return 0, ErrOverflow
+	}
+
+	newVal, err := newValue(current + delta)
+	if err != nil {
+		return 0, err
+	}
+	sh.inner[key] = newVal
+	sh.bumpVersion(key)
+	s.propagateSet(sh, key)
+
+	s.logger.Info("INCRBY executed", zap.String("key", key), zap.Int("delta", delta))
+	return current + delta, nil
+}
+
+// IncrByFloat adds delta to the number stored at key, creating it at zero if
+// it does not exist, and returns the new value. The key keeps its deadline.
+func (s *Storage) IncrByFloat(key string, delta float64) (float64, error) {
+	if err := s.checkMemory(); err != nil {
+		return 0, err
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return 0, err
+	}
+
+	current := 0.0
+	if val, ok := sh.inner[key]; ok {
+		n, ok := toFloat(val)
+		if !ok {
+			return 0, ErrNotFloat
+		}
+		current = n
+	}
+
+	result := current + delta
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, ErrOverflow
+	}
+
+	// whole results stay kindInt so that Incr keeps working on them
+	newVal := Value{Val: result, ValueType: kindFloat}
+	if isFloatInt(result) {
+		newVal.ValueType = kindInt
+	}
+	sh.inner[key] = newVal
+	sh.bumpVersion(key)
+	s.propagateSet(sh, key)
+
+	s.logger.Info("INCRBYFLOAT executed", zap.String("key", key), zap.Float64("delta", delta))
+	return result, nil
+}
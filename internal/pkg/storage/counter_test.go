@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestIncrBy(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	if n, err := s.Incr("views"); err != nil || n != 1 {
+		t.Errorf("Incr on missing key: got %d, %v", n, err)
+	}
+	if n, err := s.IncrBy("views", 10); err != nil || n != 11 {
+		t.Errorf("IncrBy: got %d, %v", n, err)
+	}
+	if n, err := s.Decr("views"); err != nil || n != 10 {
+		t.Errorf("Decr: got %d, %v", n, err)
+	}
+
+	s.Set("json", float64(4))
+	if n, err := s.Incr("json"); err != nil || n != 5 {
+		t.Errorf("Incr on float64 value: got %d, %v", n, err)
+	}
+
+	s.Set("name", "page")
+	if _, err := s.Incr("name"); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Incr on string: got %v", err)
+	}
+
+	s.Set("max", math.MaxInt)
+	if _, err := s.Incr("max"); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Incr past MaxInt: got %v", err)
+	}
+
+	s.RPUSH("list", []any{1})
+	if _, err := s.Incr("list"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("Incr on list: got %v", err)
+	}
+}
+
+func TestIncrByFloat(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	if f, err := s.IncrByFloat("price", 1.5); err != nil || f != 1.5 {
+		t.Errorf("IncrByFloat on missing key: got %v, %v", f, err)
+	}
+	if _, err := s.Incr("price"); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Incr on float: got %v", err)
+	}
+	if f, err := s.IncrByFloat("price", 0.5); err != nil || f != 2 {
+		t.Errorf("IncrByFloat: got %v, %v", f, err)
+	}
+	if n, err := s.Incr("price"); err != nil || n != 3 {
+		t.Errorf("Incr on whole float: got %d, %v", n, err)
+	}
+
+	if _, err := s.IncrByFloat("huge", 1e30); err != nil {
+		t.Fatalf("IncrByFloat past the int range: %v", err)
+	}
+	if _, err := s.Incr("huge"); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Incr past the int range: got %v", err)
+	}
+	if _, ok := toInt(Value{Val: 1e30, ValueType: kindInt}); ok {
+		t.Errorf("toInt read a float64 past the int range")
+	}
+	if _, ok := toInt(Value{Val: float64(math.MaxInt), ValueType: kindInt}); ok {
+		t.Errorf("toInt read 2^63")
+	}
+	if n, ok := toInt(Value{Val: float64(math.MinInt), ValueType: kindInt}); !ok || n != math.MinInt {
+		t.Errorf("toInt of MinInt: got %d, %v", n, ok)
+	}
+
+	s.Set("name", "page")
+	if _, err := s.IncrByFloat("name", 1); !errors.Is(err, ErrNotFloat) {
+		t.Errorf("IncrByFloat on string: got %v", err)
+	}
+}
+
+func TestIncrConcurrent(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Incr("counter")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := s.Get("counter"); v == nil || *v != 5000 {
+		t.Errorf("lost increments: got %v", v)
+	}
+}
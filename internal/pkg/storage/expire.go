@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// TTLNoExpire is returned by TTL for a key that exists but has no deadline.
+	TTLNoExpire time.Duration = -1
+	// TTLNotFound is returned by TTL for a key that does not exist.
+	TTLNotFound time.Duration = -2
+
+	expireSweepInterval = 100 * time.Millisecond
+	expireSweepSample   = 20
+)
+
+// Expire sets a time to live on the key. It returns false if the key does not exist.
+func (s *Storage) Expire(key string, d time.Duration) bool {
+	return s.ExpireAt(key, s.clock.Now().Add(d))
+}
+
+// ExpireAt sets an absolute deadline on the key. A deadline in the past removes
+// the key right away. It returns false if the key does not exist.
+func (s *Storage) ExpireAt(key string, t time.Time) bool {
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if !sh.exists(key) {
+		return false
+	}
+
+	if !t.After(s.clock.Now()) {
+		sh.removeKey(key)
+		s.propagate("DEL", key)
+		return true
+	}
+
+	sh.innerExpire[key] = t.UnixMilli()
+	sh.bumpVersion(key)
+	s.propagate("PEXPIREAT", key, t.UnixMilli())
+	s.logger.Info("expire set",
+		zap.String("key", key),
+		zap.Time("at", t))
+	return true
+}
+
+// TTL returns the remaining time to live of the key,
+// TTLNoExpire if it has no deadline or TTLNotFound if it does not exist.
+func (s *Storage) TTL(key string) time.Duration {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if !sh.exists(key) {
+		return TTLNotFound
+	}
+
+	at, ok := sh.innerExpire[key]
+	if !ok {
+		return TTLNoExpire
+	}
+
+	return time.UnixMilli(at).Sub(s.clock.Now())
+}
+
+// Persist removes the deadline from the key. It returns false if the key
+// does not exist or has no deadline.
+func (s *Storage) Persist(key string) bool {
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if !sh.exists(key) {
+		return false
+	}
+
+	if _, ok := sh.innerExpire[key]; !ok {
+		return false
+	}
+
+	delete(sh.innerExpire, key)
+	sh.bumpVersion(key)
+	s.propagate("PERSIST", key)
+	return true
+}
+
+// SetWithTTL stores a scalar value that expires after ttl.
+func (s *Storage) SetWithTTL(key string, value any, ttl time.Duration) error {
+	if err := s.checkMemory(); err != nil {
+		return err
+	}
+
+	if ttl <= 0 {
+		return errors.New("invalid expire time")
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	val, err := newValue(value)
+	if err != nil {
+		return err
+	}
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return err
+	}
+
+	sh.inner[key] = val
+	sh.innerExpire[key] = s.clock.Now().Add(ttl).UnixMilli()
+	sh.bumpVersion(key)
+	s.propagateSet(sh, key)
+
+	s.logger.Info("value set",
+		zap.String("key", key),
+		zap.String("value", string(val.ValueType)),
+		zap.Duration("ttl", ttl))
+	return nil
+}
+
+// expireIfNeeded removes the key if its deadline has passed.
+// It must be called with sh.mu held.
+func (sh *shard) expireIfNeeded(key string) bool {
+	if !sh.isExpired(key) {
+		return false
+	}
+
+	sh.removeKey(key)
+	sh.logger.Info("key expired", zap.String("key", key))
+	return true
+}
+
+// expirySweeper is the goroutine running sweepExpired.
+type expirySweeper struct {
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func newExpirySweeper() *expirySweeper {
+	return &expirySweeper{stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// stopSweeper stops the removal of expired keys in the background and waits
+// for a sweep in progress to finish. It may be called more than once.
+func (s *Storage) stopSweeper() {
+	s.sweeper.once.Do(func() { close(s.sweeper.stop) })
+	<-s.sweeper.done
+}
+
+// sweepExpired periodically removes expired keys nobody has accessed, until
+// stopSweeper is called.
+func (s *Storage) sweepExpired() {
+	defer close(s.sweeper.done)
+
+	ticker := time.NewTicker(expireSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.sweeper.stop:
+			return
+		}
+
+		for _, sh := range s.shards {
+			for sh.sweepExpiredOnce() {
+			}
+		}
+	}
+}
+
+// sweepExpiredOnce checks a sample of keys with a deadline and reports whether
+// enough of them had expired for another round to be worthwhile.
+func (sh *shard) sweepExpiredOnce() bool {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	now := sh.clock.Now().UnixMilli()
+	checked, expired := 0, 0
+	for key, at := range sh.innerExpire {
+		if checked == expireSweepSample {
+			break
+		}
+		checked++
+
+		if at <= now {
+			sh.removeKey(key)
+			expired++
+		}
+	}
+
+	if expired > 0 {
+		sh.logger.Info("expired keys swept", zap.Int("count", expired))
+	}
+
+	return checked == expireSweepSample && expired*4 > checked
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpire(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.Set("scalar", "value")
+	s.RPUSH("list", []any{1, 2, 3})
+	s.HSET("hash", "field", "value")
+
+	for _, key := range []string{"scalar", "list", "hash"} {
+		t.Run(key, func(t *testing.T) {
+			if !s.Expire(key, 50*time.Millisecond) {
+				t.Fatalf("expire on existing key failed")
+			}
+
+			if ttl := s.TTL(key); ttl <= 0 || ttl > 50*time.Millisecond {
+				t.Errorf("unexpected ttl %v", ttl)
+			}
+
+			time.Sleep(60 * time.Millisecond)
+
+			if ttl := s.TTL(key); ttl != TTLNotFound {
+				t.Errorf("key still alive, ttl %v", ttl)
+			}
+		})
+	}
+
+	if s.Get("scalar") != nil {
+		t.Errorf("expired scalar returned")
+	}
+	if _, err := s.LGET("list", 0); err == nil {
+		t.Errorf("expired list returned")
+	}
+	if s.HGET("hash", "field") != nil {
+		t.Errorf("expired hash returned")
+	}
+	if s.Expire("missing", time.Second) {
+		t.Errorf("expire on missing key succeeded")
+	}
+}
+
+func TestPersist(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.Set("key", 1)
+	if s.TTL("key") != TTLNoExpire {
+		t.Errorf("new key has a deadline")
+	}
+	if s.Persist("key") {
+		t.Errorf("persist on key without deadline succeeded")
+	}
+
+	s.Expire("key", 20*time.Millisecond)
+	if !s.Persist("key") {
+		t.Errorf("persist failed")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if s.Get("key") == nil {
+		t.Errorf("persisted key expired")
+	}
+}
+
+func TestSetWithTTL(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	if err := s.SetWithTTL("key", "value", 0); err == nil {
+		t.Errorf("zero ttl accepted")
+	}
+
+	if err := s.SetWithTTL("key", "value", 20*time.Millisecond); err != nil {
+		t.Fatalf("set with ttl: %v", err)
+	}
+	if s.Get("key") == nil {
+		t.Errorf("value not set")
+	}
+
+	s.Set("key", "other")
+	if s.TTL("key") != TTLNoExpire {
+		t.Errorf("Set kept the old deadline")
+	}
+}
+
+func TestExpireSweeper(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		s.SetWithTTL(key, key, 10*time.Millisecond)
+	}
+
+	time.Sleep(3 * expireSweepInterval)
+
+	defer s.rlockAll()()
+	for _, sh := range s.shards {
+		if len(sh.inner) != 0 || len(sh.innerExpire) != 0 {
+			t.Errorf("sweeper left %d keys", len(sh.inner))
+		}
+	}
+}
+
+func TestStopSweeper(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.stopSweeper()
+	s.stopSweeper()
+
+	s.SetWithTTL("a", "a", time.Millisecond)
+	time.Sleep(3 * expireSweepInterval)
+
+	sh := s.shard("a")
+	defer s.rlockShard(sh)()
+	if len(sh.innerExpire) != 1 {
+		t.Errorf("stopped sweeper removed the key")
+	}
+}
@@ -1,79 +1,272 @@
 package storage
 
 import (
-	"encoding/json"
+	"bufio"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
 	"go.uber.org/zap"
 )
 
-type storageFile struct {
-	Inner map[string]Value `json:"inner"`
-	List  map[string][]any `json:"list"`
-}
+// SnapshotFormat is the encoding of a snapshot file.
+type SnapshotFormat string
+
+const (
+	// FormatJSON is an indented JSON document, easy to read and to edit.
+	FormatJSON SnapshotFormat = "json"
+	// FormatBinary is a compact, checksummed binary file, faster to write
+	// and to read and streamed one key at a time.
+	FormatBinary SnapshotFormat = "binary"
+)
 
+// SaveToFile saves a snapshot to path in the JSON format.
 func (s *Storage) SaveToFile(path string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return s.SaveSnapshot(path, FormatJSON)
+}
 
-	data := storageFile{
-		Inner: s.inner,
-		List:  make(map[string][]any),
+// SaveSnapshot writes every key with its type, value and deadline to path,
+// in the snapshot format of snapshotVersion encoded as format. The file holds
+// the storage at the instant the save began: the shards are read one key at a
+// time, and a key changed meanwhile is saved as it was, from a copy made
+// before its first change. Keys are encoded to the file as they are read, so
+// a save needs memory for one key and the copies of the keys changed while it
+// runs. Snapshots are saved one at a time. The file is written next to path
+// and renamed over it, so a crash never leaves a half-written file at path.
+func (s *Storage) SaveSnapshot(path string, format SnapshotFormat) error {
+	var newEncoder func(w io.Writer) (snapshotEncoder, error)
+	switch format {
+	case FormatJSON:
+		newEncoder = newJSONEncoder
+	case FormatBinary:
+		newEncoder = newBinaryEncoder
+	default:
+		return fmt.Errorf("unknown snapshot format %q", format)
 	}
 
-	for k, v := range s.list {
-		data.List[k] = v.Elem
-	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
 
-	jsonData, err := json.MarshalIndent(data, "", "\t")
-	if err != nil {
-		return fmt.Errorf("failed to marshal data: %w", err)
-	}
+	var dirty int64
+	keys := 0
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		enc, err := newEncoder(w)
+		if err != nil {
+			return err
+		}
 
-	err = os.WriteFile(path, jsonData, 0644)
+		// changes made from now on are not in the file
+		dirty = s.beginView()
+		err = s.readView(func(k *snapshotKey) error {
+			keys++
+			return enc.writeKey(k)
+		})
+		if err != nil {
+			return err
+		}
+		return enc.close()
+	}, s.logger)
 	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		return err
 	}
+	s.clearDirty(dirty)
 
-	s.logger.Info("Storage saved to file", zap.String("file", path))
+	s.logger.Info("Storage saved to file",
+		zap.String("file", path),
+		zap.String("format", string(format)),
+		zap.Int("keys", keys))
 	return nil
 }
 
+// clearDirty takes n saved changes off the count of changes not yet in a
+// snapshot, which never goes below zero.
+func (s *Storage) clearDirty(n int64) {
+	for {
+		cur := s.dirty.Load()
+		if s.dirty.CompareAndSwap(cur, max(cur-n, 0)) {
+			return
+		}
+	}
+}
+
+// LoadMode tells a load what to do with the keys the storage already has.
+type LoadMode string
+
+const (
+	// LoadReplace removes the keys of the storage the filter matches, then
+	// loads the keys of the snapshot.
+	LoadReplace LoadMode = "replace"
+	// LoadMergeKeep loads the keys of the snapshot the storage does not
+	// have, and keeps the others as they are.
+	LoadMergeKeep LoadMode = "merge-keep"
+	// LoadMergeOverwrite loads every key of the snapshot over the one of the
+	// storage, and keeps the keys the snapshot does not have.
+	LoadMergeOverwrite LoadMode = "merge-overwrite"
+)
+
+// LoadOptions tells LoadSnapshot how to load a snapshot.
+type LoadOptions struct {
+	// Mode is LoadReplace if empty.
+	Mode LoadMode
+	// Match is a glob pattern, as in Scan, restricting the load to the keys
+	// it matches, on both sides. An empty pattern matches every key.
+	Match string
+	// DryRun reports what the load would change without changing anything.
+	DryRun bool
+}
+
+// LoadReport lists the keys a load changed, or would have changed on a dry run.
+type LoadReport struct {
+	DryRun      bool     `json:"dryRun"`
+	Added       []string `json:"added"`
+	Overwritten []string `json:"overwritten"`
+	Removed     []string `json:"removed"`
+	// Kept are keys of the snapshot left alone, the storage had them.
+	Kept []string `json:"kept"`
+	// Skipped counts the keys of the snapshot the filter left out or whose
+	// deadline has passed.
+	Skipped int `json:"skipped"`
+}
+
+// LoadFromFile replaces the keyspace with the keys saved to path by
+// SaveSnapshot in either format, by this version or an older one. Keys whose
+// deadline passed while they were on disk are left out.
 func (s *Storage) LoadFromFile(path string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	_, err := s.LoadFromFileWithOptions(path, LoadOptions{})
+	return err
+}
+
+// LoadFromFileWithOptions loads the snapshot saved to path as opts say.
+func (s *Storage) LoadFromFileWithOptions(path string, opts LoadOptions) (*LoadReport, error) {
+	if s.tx != nil {
+		return nil, ErrInTransaction
+	}
 
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return fmt.Errorf("file does not exist: %w", err)
+		return nil, fmt.Errorf("file does not exist: %w", err)
 	}
 
 	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	jsonData, err := io.ReadAll(file)
+	report, err := s.LoadSnapshot(file, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Storage loaded from file", zap.String("file", path))
+	return report, nil
+}
+
+// LoadSnapshot loads a snapshot in either format from r as opts say. Each key
+// is checked and staged as it is read, and the storage is locked only once
+// the whole snapshot has been read, so a damaged snapshot changes nothing,
+// and the storage goes from its state before the load to its state after it
+// at once.
+func (s *Storage) LoadSnapshot(r io.Reader, opts LoadOptions) (*LoadReport, error) {
+	if s.tx != nil {
+		return nil, ErrInTransaction
+	}
+	switch opts.Mode {
+	case "":
+		opts.Mode = LoadReplace
+	case LoadReplace, LoadMergeKeep, LoadMergeOverwrite:
+	default:
+		return nil, fmt.Errorf("unknown load mode %q", opts.Mode)
+	}
+
+	stage := s.newSnapshotStage(opts)
+	if err := readSnapshot(bufio.NewReader(r), stage); err != nil {
+		return nil, err
+	}
+
+	unlock := s.lockAll()
+	report := s.applySnapshot(stage)
+	unlock()
+
+	if !report.DryRun {
+		// clients blocked on a list the load brought get its elements
+		for _, key := range append(report.Added, report.Overwritten...) {
+			s.serveWaiters(key)
+		}
+	}
+
+	s.logger.Info("snapshot loaded",
+		zap.String("mode", string(opts.Mode)),
+		zap.String("match", opts.Match),
+		zap.Bool("dry_run", opts.DryRun),
+		zap.Int("added", len(report.Added)),
+		zap.Int("overwritten", len(report.Overwritten)),
+		zap.Int("removed", len(report.Removed)),
+		zap.Int("kept", len(report.Kept)))
+	return report, nil
+}
+
+// readSnapshot decodes a snapshot in the format it finds in r into stage.
+// A binary snapshot is staged one section at a time, a JSON one is decoded
+// whole first.
+func readSnapshot(r *bufio.Reader, stage *snapshotStage) error {
+	if isBinarySnapshot(r) {
+		if err := decodeBinarySnapshot(r, stage.add); err != nil {
+			return fmt.Errorf("failed to decode data: %w", err)
+		}
+		return nil
+	}
+
+	jsonData, err := io.ReadAll(r)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	var data storageFile
-	if err := json.Unmarshal(jsonData, &data); err != nil {
+	data, err := decodeSnapshot(jsonData)
+	if err != nil {
 		return fmt.Errorf("failed to unmarshal data: %w", err)
 	}
+	for i := range data.Keys {
+		stage.add(data.Keys[i])
+		data.Keys[i] = snapshotKey{}
+	}
+	return nil
+}
 
-	s.inner = data.Inner
-	s.list = make(map[string]*List)
+// writeFileAtomic has write fill a temporary file in the directory of path,
+// syncs it and renames it over path. On failure path is left as it was.
+func writeFileAtomic(path string, write func(w io.Writer) error, logger *zap.Logger) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
 
-	for k, v := range data.List {
-		s.list[k] = &List{Elem: v}
+	w := bufio.NewWriter(tmp)
+	if err := write(w); err != nil {
+		return fmt.Errorf("failed to marshal data: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
 	}
+	if err := tmp.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	syncDir(filepath.Dir(path), logger)
 
-	s.logger.Info("Storage loaded from file",
-		zap.String("file", path),
-		zap.Int("items_loaded", len(s.inner)+len(s.list)))
 	return nil
 }
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// ErrNotInteger is returned when arithmetic is asked of a value that is not of kindInt.
+var ErrNotInteger = errors.New("value is not an integer")
+
+// HDEL removes the given fields and returns how many of them existed.
+func (s *Storage) HDEL(key string, fields ...string) (int, error) {
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeHash); err != nil {
+		return 0, err
+	}
+	defer sh.removeIfEmpty(key)
+
+	hash := sh.innerMap[key]
+	count := 0
+	for _, field := range fields {
+		if _, ok := hash[field]; ok {
+			delete(hash, field)
+			count++
+		}
+	}
+	if count > 0 {
+		sh.bumpVersion(key)
+		s.propagate(append([]any{"HDEL", key}, stringArgs(fields)...)...)
+	}
+
+	s.logger.Info("HDEL executed", zap.String("key", key), zap.Int("deleted", count))
+	return count, nil
+}
+
+// HGETALL returns every field of the hash with its value.
+func (s *Storage) HGETALL(key string) (map[string]any, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeHash); err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]any, len(sh.innerMap[key]))
+	for field, val := range sh.innerMap[key] {
+		res[field] = val.Val
+	}
+
+	return res, nil
+}
+
+// HKEYS returns the field names of the hash in sorted order.
+func (s *Storage) HKEYS(key string) ([]string, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeHash); err != nil {
+		return nil, err
+	}
+
+	return sh.hkeys(key), nil
+}
+
+// HVALS returns the values of the hash ordered by field name.
+func (s *Storage) HVALS(key string) ([]any, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeHash); err != nil {
+		return nil, err
+	}
+
+	hash := sh.innerMap[key]
+	res := make([]any, 0, len(hash))
+	for _, field := range sh.hkeys(key) {
+		res = append(res, hash[field].Val)
+	}
+
+	return res, nil
+}
+
+// HLEN returns the number of fields in the hash.
+func (s *Storage) HLEN(key string) (int, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeHash); err != nil {
+		return 0, err
+	}
+
+	return len(sh.innerMap[key]), nil
+}
+
+// HEXISTS reports whether the hash has the field.
+func (s *Storage) HEXISTS(key string, field string) (bool, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeHash); err != nil {
+		return false, err
+	}
+
+	_, ok := sh.innerMap[key][field]
+	return ok, nil
+}
+
+// HINCRBY adds delta to an integer field, creating the hash and the field at zero
+// if needed, and returns the new value.
+func (s *Storage) HINCRBY(key string, field string, delta int) (int, error) {
+	if err := s.checkMemory(); err != nil {
+		return 0, err
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeHash); err != nil {
+		return 0, err
+	}
+
+	current := 0
+	if val, ok := sh.innerMap[key][field]; ok {
+		n, ok := toInt(val)
+		if !ok {
+			return 0, ErrNotInteger
+		}
+		current = n
+	}
+
+	if (delta > 0 && current > math.MaxInt-delta) || (delta < 0 && current < math.MinInt-delta) {
+		return 0, ErrOverflow
+	}
+
+	newVal, err := newValue(current + delta)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, ok := sh.innerMap[key]; !ok {
+		sh.innerMap[key] = make(map[string]Value)
+	}
+	sh.innerMap[key][field] = newVal
+	sh.bumpVersion(key)
+	s.propagate("HSET", key, field, newVal.Val)
+
+	s.logger.Info("HINCRBY executed", zap.String("key", key), zap.String("field", field))
+	return current + delta, nil
+}
+
+// hkeys must be called with sh.mu held.
+func (sh *shard) hkeys(key string) []string {
+	res := make([]string, 0, len(sh.innerMap[key]))
+	for field := range sh.innerMap[key] {
+		res = append(res, field)
+	}
+	sort.Strings(res)
+
+	return res
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestHashCommands(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.HSET("user", "name", "ann")
+	s.HSET("user", "age", 31)
+	s.HSET("user", "city", "oslo")
+
+	if v := s.HGET("user", "name"); v == nil || *v != "ann" {
+		t.Errorf("HGET: got %v", v)
+	}
+
+	all, err := s.HGETALL("user")
+	if err != nil || !reflect.DeepEqual(all, map[string]any{"name": "ann", "age": 31, "city": "oslo"}) {
+		t.Errorf("HGETALL: got %v, %v", all, err)
+	}
+
+	keys, _ := s.HKEYS("user")
+	if !reflect.DeepEqual(keys, []string{"age", "city", "name"}) {
+		t.Errorf("HKEYS: got %v", keys)
+	}
+
+	vals, _ := s.HVALS("user")
+	if !reflect.DeepEqual(vals, []any{31, "oslo", "ann"}) {
+		t.Errorf("HVALS: got %v", vals)
+	}
+
+	if n, _ := s.HLEN("user"); n != 3 {
+		t.Errorf("HLEN: got %d", n)
+	}
+
+	if ok, _ := s.HEXISTS("user", "city"); !ok {
+		t.Errorf("HEXISTS: field missing")
+	}
+
+	if n, _ := s.HDEL("user", "city", "missing"); n != 1 {
+		t.Errorf("HDEL: got %d", n)
+	}
+	if ok, _ := s.HEXISTS("user", "city"); ok {
+		t.Errorf("HDEL: field still there")
+	}
+
+	s.HDEL("user", "name", "age")
+	if s.Exists("user") != 0 {
+		t.Errorf("empty hash still exists")
+	}
+}
+
+func TestHINCRBY(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	if n, err := s.HINCRBY("stats", "views", 5); err != nil || n != 5 {
+		t.Errorf("HINCRBY on missing field: got %d, %v", n, err)
+	}
+
+	s.HSET("stats", "likes", float64(10))
+	if n, err := s.HINCRBY("stats", "likes", -3); err != nil || n != 7 {
+		t.Errorf("HINCRBY on float field: got %d, %v", n, err)
+	}
+
+	s.HSET("stats", "name", "page")
+	if _, err := s.HINCRBY("stats", "name", 1); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("HINCRBY on string field: got %v", err)
+	}
+
+	s.HSET("stats", "max", math.MaxInt)
+	if _, err := s.HINCRBY("stats", "max", 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("HINCRBY past MaxInt: got %v", err)
+	}
+	s.HSET("stats", "min", math.MinInt)
+	if _, err := s.HINCRBY("stats", "min", -1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("HINCRBY past MinInt: got %v", err)
+	}
+	if v := s.HGET("stats", "max"); v == nil || *v != math.MaxInt {
+		t.Errorf("HINCRBY overflow changed the field: got %v", v)
+	}
+
+	s.Set("scalar", 1)
+	if _, err := s.HINCRBY("scalar", "field", 1); !errors.Is(err, ErrWrongType) {
+		t.Errorf("HINCRBY on scalar: got %v", err)
+	}
+}
@@ -0,0 +1,412 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrPathNotFound is returned when a JSON path does not lead to a value.
+	ErrPathNotFound = errors.New("path does not exist")
+	// ErrNotArray is returned when an array operation meets another kind of value.
+	ErrNotArray = errors.New("value at path is not an array")
+)
+
+// pathStep is one step of a JSON path, either an object field or an array index.
+type pathStep struct {
+	field   string
+	index   int
+	isIndex bool
+}
+
+// JSONGet returns a copy of the value at path in the document stored at key,
+// nil if the key or the path does not exist.
+//
+// Paths are a JSONPath subset: "$" is the whole value and is followed by
+// ".name", "['name']" or "[index]" steps. Negative indexes count from the end.
+func (s *Storage) JSONGet(key, path string) (*any, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return nil, err
+	}
+
+	root, ok := sh.inner[key]
+	if !ok {
+		return nil, nil
+	}
+
+	val, ok := lookupPath(root.Val, steps)
+	if !ok {
+		return nil, nil
+	}
+
+	val = cloneJSON(val)
+	return &val, nil
+}
+
+// JSONSet stores value at path. The root path creates or replaces the whole
+// value, other paths need an existing parent and may add a new object field.
+// The key keeps its deadline.
+func (s *Storage) JSONSet(key, path string, value any) error {
+	if err := s.checkMemory(); err != nil {
+		return err
+	}
+
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return err
+	}
+	if _, err := newValue(value); err != nil {
+		return err
+	}
+	value = cloneJSON(value)
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return err
+	}
+
+	root, ok := sh.inner[key]
+	if !ok && len(steps) > 0 {
+		return ErrPathNotFound
+	}
+
+	newRoot, err := updatePath(root.Val, steps, func(any, bool) (any, error) {
+		return value, nil
+	})
+	if err != nil {
+		return err
+	}
+	sh.setDocument(key, newRoot)
+	s.propagateSet(sh, key)
+
+	s.logger.Info("JSON.SET executed", zap.String("key", key), zap.String("path", path))
+	return nil
+}
+
+// JSONDel removes the value at path and returns how many values were removed.
+// Deleting the root path deletes the key.
+func (s *Storage) JSONDel(key, path string) (int, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return 0, err
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return 0, err
+	}
+
+	root, ok := sh.inner[key]
+	if !ok {
+		return 0, nil
+	}
+	if _, ok := lookupPath(root.Val, steps); !ok {
+		return 0, nil
+	}
+
+	if len(steps) == 0 {
+		sh.removeKey(key)
+		s.propagate("DEL", key)
+	} else {
+		last := steps[len(steps)-1]
+		newRoot, err := updatePath(root.Val, steps[:len(steps)-1], func(parent any, _ bool) (any, error) {
+			return removeStep(parent, last), nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		sh.setDocument(key, newRoot)
+		s.propagateSet(sh, key)
+	}
+
+	s.logger.Info("JSON.DEL executed", zap.String("key", key), zap.String("path", path))
+	return 1, nil
+}
+
+// JSONArrAppend appends values to the array at path and returns its new length.
+func (s *Storage) JSONArrAppend(key, path string, values ...any) (int, error) {
+	if err := s.checkMemory(); err != nil {
+		return 0, err
+	}
+
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return 0, errors.New("WrongArgs")
+	}
+	for i, val := range values {
+		if _, err := newValue(val); err != nil {
+			return 0, err
+		}
+		values[i] = cloneJSON(val)
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return 0, err
+	}
+
+	root, ok := sh.inner[key]
+	if !ok {
+		return 0, ErrPathNotFound
+	}
+
+	length := 0
+	newRoot, err := updatePath(root.Val, steps, func(old any, ok bool) (any, error) {
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+		arr, ok := old.([]any)
+		if !ok {
+			return nil, ErrNotArray
+		}
+
+		arr = append(arr, values...)
+		length = len(arr)
+		return arr, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	sh.setDocument(key, newRoot)
+	s.propagateSet(sh, key)
+
+	s.logger.Info("JSON.ARRAPPEND executed", zap.String("key", key), zap.String("path", path))
+	return length, nil
+}
+
+// JSONNumIncrBy adds delta to the number at path and returns the new number.
+// Integers stay integers as long as delta is whole.
+func (s *Storage) JSONNumIncrBy(key, path string, delta float64) (float64, error) {
+	if err := s.checkMemory(); err != nil {
+		return 0, err
+	}
+
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return 0, err
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return 0, err
+	}
+
+	root, ok := sh.inner[key]
+	if !ok {
+		return 0, ErrPathNotFound
+	}
+
+	var result float64
+	newRoot, err := updatePath(root.Val, steps, func(old any, ok bool) (any, error) {
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+		current, ok := number(old)
+		if !ok {
+			return nil, ErrNotFloat
+		}
+
+		result = current + delta
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			return nil, ErrNotFloat
+		}
+
+		if _, isInt := old.(int); isInt && result == math.Trunc(result) && math.Abs(result) < 1<<53 {
+			return int(result), nil
+		}
+		return result, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	sh.setDocument(key, newRoot)
+	s.propagateSet(sh, key)
+
+	s.logger.Info("JSON.NUMINCRBY executed", zap.String("key", key), zap.String("path", path))
+	return result, nil
+}
+
+// setDocument stores a root already validated and owned by the storage. It
+// must be called with sh.mu held.
+func (sh *shard) setDocument(key string, root any) {
+	sh.inner[key] = Value{
+		Val:       root,
+		ValueType: getType(root),
+	}
+	sh.bumpVersion(key)
+}
+
+func parseJSONPath(path string) ([]pathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, ErrSyntax
+	}
+	rest := path[1:]
+
+	steps := []pathStep{}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			if end == 1 {
+				return nil, ErrSyntax
+			}
+			steps = append(steps, pathStep{field: rest[1:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, ErrSyntax
+			}
+			step, err := parseBracket(rest[1:end])
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			rest = rest[end+1:]
+		default:
+			return nil, ErrSyntax
+		}
+	}
+
+	return steps, nil
+}
+
+// parseBracket parses the inside of a "[...]" step, a quoted field or an index.
+func parseBracket(inner string) (pathStep, error) {
+	if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+		return pathStep{field: inner[1 : len(inner)-1]}, nil
+	}
+
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return pathStep{}, ErrSyntax
+	}
+
+	return pathStep{index: index, isIndex: true}, nil
+}
+
+// lookupPath follows steps from val and reports whether they all exist.
+func lookupPath(val any, steps []pathStep) (any, bool) {
+	for _, step := range steps {
+		var ok bool
+		if val, ok = child(val, step); !ok {
+			return nil, false
+		}
+	}
+
+	return val, true
+}
+
+func child(val any, step pathStep) (any, bool) {
+	switch v := val.(type) {
+	case map[string]any:
+		if step.isIndex {
+			return nil, false
+		}
+		elem, ok := v[step.field]
+		return elem, ok
+	case []any:
+		if !step.isIndex {
+			return nil, false
+		}
+		i, ok := arrayIndex(step.index, len(v))
+		if !ok {
+			return nil, false
+		}
+		return v[i], true
+	}
+
+	return nil, false
+}
+
+// updatePath replaces the value at steps in a copy of root by the result of
+// f and returns the new root. f gets the old value and whether it exists,
+// which can only be false for a missing object field. The stored document is
+// never changed in place because Get hands it out without copying.
+func updatePath(root any, steps []pathStep, f func(old any, ok bool) (any, error)) (any, error) {
+	root = cloneJSON(root)
+	if len(steps) == 0 {
+		return f(root, true)
+	}
+
+	parent, ok := lookupPath(root, steps[:len(steps)-1])
+	if !ok {
+		return nil, ErrPathNotFound
+	}
+
+	last := steps[len(steps)-1]
+	switch p := parent.(type) {
+	case map[string]any:
+		if last.isIndex {
+			return nil, ErrPathNotFound
+		}
+		old, ok := p[last.field]
+		val, err := f(old, ok)
+		if err != nil {
+			return nil, err
+		}
+		p[last.field] = val
+	case []any:
+		i, ok := arrayIndex(last.index, len(p))
+		if !last.isIndex || !ok {
+			return nil, ErrPathNotFound
+		}
+		val, err := f(p[i], true)
+		if err != nil {
+			return nil, err
+		}
+		p[i] = val
+	default:
+		return nil, ErrPathNotFound
+	}
+
+	return root, nil
+}
+
+// arrayIndex resolves a possibly negative index, false if it is out of range.
+func arrayIndex(index, length int) (int, bool) {
+	if index < 0 {
+		index += length
+	}
+
+	return index, index >= 0 && index < length
+}
+
+// removeStep returns container without the child at step, which must exist.
+func removeStep(container any, step pathStep) any {
+	switch c := container.(type) {
+	case map[string]any:
+		delete(c, step.field)
+	case []any:
+		i, _ := arrayIndex(step.index, len(c))
+		return append(c[:i:i], c[i+1:]...)
+	}
+
+	return container
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newConfig(t *testing.T) Storage {
+	t.Helper()
+
+	s, _ := NewStorage()
+	doc := map[string]any{
+		"name":  "api",
+		"port":  8080,
+		"hosts": []any{"a", "b"},
+		"limits": map[string]any{
+			"rate": 1.5,
+		},
+	}
+	if err := s.JSONSet("config", "$", doc); err != nil {
+		t.Fatalf("JSONSet root: %v", err)
+	}
+
+	return s
+}
+
+func TestJSONGet(t *testing.T) {
+	s := newConfig(t)
+
+	cases := []struct {
+		path string
+		want any
+	}{
+		{"$.name", "api"},
+		{"$.port", 8080},
+		{"$.hosts[1]", "b"},
+		{"$.hosts[-1]", "b"},
+		{"$['limits'].rate", 1.5},
+		{`$["hosts"][0]`, "a"},
+	}
+
+	for _, c := range cases {
+		got, err := s.JSONGet("config", c.path)
+		if err != nil || got == nil || !reflect.DeepEqual(*got, c.want) {
+			t.Errorf("JSONGet(%q) = %v, %v, want %v", c.path, got, err, c.want)
+		}
+	}
+
+	if got, _ := s.JSONGet("config", "$.missing.field"); got != nil {
+		t.Errorf("missing path = %v, want nil", *got)
+	}
+	if got, _ := s.JSONGet("nokey", "$"); got != nil {
+		t.Errorf("missing key = %v, want nil", *got)
+	}
+	for _, path := range []string{"name", "$.", "$[x]", "$[0", "$name"} {
+		if _, err := s.JSONGet("config", path); err != ErrSyntax {
+			t.Errorf("JSONGet(%q) error = %v, want ErrSyntax", path, err)
+		}
+	}
+}
+
+func TestJSONSet(t *testing.T) {
+	s := newConfig(t)
+
+	before, _ := s.JSONGet("config", "$")
+
+	if err := s.JSONSet("config", "$.limits.burst", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.JSONSet("config", "$.hosts[0]", "c"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := s.JSONGet("config", "$.limits")
+	if want := map[string]any{"rate": 1.5, "burst": 10}; !reflect.DeepEqual(*got, want) {
+		t.Errorf("limits = %v, want %v", *got, want)
+	}
+	if (*before).(map[string]any)["hosts"].([]any)[0] != "a" {
+		t.Errorf("a value returned before the update was changed")
+	}
+
+	if err := s.JSONSet("config", "$.hosts[5]", "x"); err != ErrPathNotFound {
+		t.Errorf("out of range index error = %v, want ErrPathNotFound", err)
+	}
+	if err := s.JSONSet("config", "$.a.b", 1); err != ErrPathNotFound {
+		t.Errorf("missing parent error = %v, want ErrPathNotFound", err)
+	}
+	if err := s.JSONSet("nokey", "$.a", 1); err != ErrPathNotFound {
+		t.Errorf("missing key error = %v, want ErrPathNotFound", err)
+	}
+
+	s.LPUSH("list", []any{1})
+	if err := s.JSONSet("list", "$", 1); !errors.Is(err, ErrWrongType) {
+		t.Errorf("list error = %v, want ErrWrongType", err)
+	}
+}
+
+func TestJSONDel(t *testing.T) {
+	s := newConfig(t)
+
+	if n, err := s.JSONDel("config", "$.hosts[0]"); n != 1 || err != nil {
+		t.Fatalf("JSONDel = %d, %v", n, err)
+	}
+	if n, _ := s.JSONDel("config", "$.limits.none"); n != 0 {
+		t.Errorf("deleting a missing path = %d, want 0", n)
+	}
+
+	got, _ := s.JSONGet("config", "$.hosts")
+	if want := []any{"b"}; !reflect.DeepEqual(*got, want) {
+		t.Errorf("hosts = %v, want %v", *got, want)
+	}
+
+	if n, _ := s.JSONDel("config", "$"); n != 1 || s.Exists("config") != 0 {
+		t.Errorf("deleting the root must delete the key")
+	}
+}
+
+func TestJSONArrAppendAndNumIncrBy(t *testing.T) {
+	s := newConfig(t)
+
+	if n, err := s.JSONArrAppend("config", "$.hosts", "c", map[string]any{"d": true}); n != 4 || err != nil {
+		t.Errorf("JSONArrAppend = %d, %v, want 4", n, err)
+	}
+	if _, err := s.JSONArrAppend("config", "$.name", "x"); err != ErrNotArray {
+		t.Errorf("appending to a string error = %v, want ErrNotArray", err)
+	}
+
+	if n, err := s.JSONNumIncrBy("config", "$.port", 1); n != 8081 || err != nil {
+		t.Errorf("JSONNumIncrBy = %v, %v, want 8081", n, err)
+	}
+	if got, _ := s.JSONGet("config", "$.port"); *got != 8081 {
+		t.Errorf("port = %#v, want int 8081", *got)
+	}
+	if n, _ := s.JSONNumIncrBy("config", "$.limits.rate", 0.25); n != 1.75 {
+		t.Errorf("rate = %v, want 1.75", n)
+	}
+	if _, err := s.JSONNumIncrBy("config", "$.name", 1); err != ErrNotFloat {
+		t.Errorf("incrementing a string error = %v, want ErrNotFloat", err)
+	}
+}
+
+func TestJSONSaveLoad(t *testing.T) {
+	s := newConfig(t)
+	s.JSONArrAppend("config", "$.hosts", nil)
+
+	path := filepath.Join(t.TempDir(), "storage.json")
+	if err := s.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _ := NewStorage()
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := s.JSONGet("config", "$")
+	got, _ := loaded.JSONGet("config", "$")
+	if got == nil || !reflect.DeepEqual(*got, *want) {
+		t.Errorf("document after load = %v, want %v", got, *want)
+	}
+	if loaded.shard("config").inner["config"].ValueType != kindJSON {
+		t.Errorf("kind after load = %q, want %q", loaded.shard("config").inner["config"].ValueType, kindJSON)
+	}
+}
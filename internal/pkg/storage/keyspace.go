@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// KeyType is the type of the value a key holds in the keyspace.
+type KeyType string
+
+const (
+	TypeNone   KeyType = "none"
+	TypeString KeyType = "string"
+	TypeList   KeyType = "list"
+	TypeHash   KeyType = "hash"
+	TypeSet    KeyType = "set"
+	TypeZSet   KeyType = "zset"
+)
+
+// ErrWrongType is matched by every WrongTypeError via errors.Is.
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// WrongTypeError is returned when an operation for one type is run against
+// a key that holds another.
+type WrongTypeError struct {
+	Key  string
+	Want KeyType
+	Have KeyType
+}
+
+func (e *WrongTypeError) Error() string {
+	return fmt.Sprintf("WRONGTYPE key %q holds a %s, not a %s", e.Key, e.Have, e.Want)
+}
+
+func (e *WrongTypeError) Is(target error) bool {
+	return target == ErrWrongType
+}
+
+// Type returns the type of the value stored at key, TypeNone if it does not exist.
+func (s *Storage) Type(key string) KeyType {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	return sh.keyType(key)
+}
+
+// Exists returns how many of the given keys exist. A key mentioned
+// several times is counted several times.
+func (s *Storage) Exists(keys ...string) int {
+	defer s.rlockKeys(keys...)()
+
+	count := 0
+	for _, key := range keys {
+		if s.shard(key).exists(key) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Del removes the given keys whatever their type and returns how many existed.
+func (s *Storage) Del(keys ...string) int {
+	defer s.lockKeys(keys...)()
+
+	count := 0
+	for _, key := range keys {
+		if sh := s.shard(key); sh.exists(key) {
+			sh.removeKey(key)
+			count++
+		}
+	}
+
+	if count > 0 {
+		s.propagate(append([]any{"DEL"}, stringArgs(keys)...)...)
+	}
+
+	s.logger.Info("DEL executed", zap.Int("deleted", count))
+	return count
+}
+
+// flushAll removes every key.
+func (s *Storage) flushAll() {
+	defer s.lockAll()()
+
+	for _, sh := range s.shards {
+		sh.preserveAll()
+		clear(sh.inner)
+		clear(sh.list)
+		clear(sh.innerMap)
+		clear(sh.sets)
+		clear(sh.zsets)
+		clear(sh.innerExpire)
+		clear(sh.versions)
+	}
+}
+
+// keyType must be called with sh.mu held, after lock or rlock dropped the key
+// if it had expired.
+func (sh *shard) keyType(key string) KeyType {
+	if _, ok := sh.inner[key]; ok {
+		return TypeString
+	}
+	if _, ok := sh.list[key]; ok {
+		return TypeList
+	}
+	if _, ok := sh.innerMap[key]; ok {
+		return TypeHash
+	}
+	if _, ok := sh.sets[key]; ok {
+		return TypeSet
+	}
+	if _, ok := sh.zsets[key]; ok {
+		return TypeZSet
+	}
+
+	return TypeNone
+}
+
+// checkType fails with a WrongTypeError if the key exists with a type other
+// than want. It must be called with sh.mu held.
+func (sh *shard) checkType(key string, want KeyType) error {
+	have := sh.keyType(key)
+	if have != TypeNone && have != want {
+		return &WrongTypeError{Key: key, Want: want, Have: have}
+	}
+
+	return nil
+}
+
+// exists reports whether the key holds a live value of any type.
+// It must be called with sh.mu held.
+func (sh *shard) exists(key string) bool {
+	return sh.keyType(key) != TypeNone
+}
+
+// removeKey drops the key from every map. It must be called with sh.mu held.
+func (sh *shard) removeKey(key string) {
+	delete(sh.inner, key)
+	delete(sh.list, key)
+	delete(sh.innerMap, key)
+	delete(sh.sets, key)
+	delete(sh.zsets, key)
+	delete(sh.innerExpire, key)
+	delete(sh.versions, key)
+}
+
+// removeIfEmpty drops a list, hash, set or sorted set left without elements, so that an empty
+// container never shows up as an existing key. It must be called with sh.mu held.
+func (sh *shard) removeIfEmpty(key string) {
+	if list, ok := sh.list[key]; ok && len(list.Elem) == 0 {
+		sh.removeKey(key)
+	}
+	if hash, ok := sh.innerMap[key]; ok && len(hash) == 0 {
+		sh.removeKey(key)
+	}
+	if set, ok := sh.sets[key]; ok && len(set) == 0 {
+		sh.removeKey(key)
+	}
+	if zs, ok := sh.zsets[key]; ok && len(zs.dict) == 0 {
+		sh.removeKey(key)
+	}
+}
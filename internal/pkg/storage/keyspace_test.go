@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestType(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.Set("scalar", "value")
+	s.RPUSH("list", []any{1})
+	s.HSET("hash", "field", 1)
+
+	cases := map[string]KeyType{
+		"scalar":  TypeString,
+		"list":    TypeList,
+		"hash":    TypeHash,
+		"missing": TypeNone,
+	}
+
+	for key, want := range cases {
+		if got := s.Type(key); got != want {
+			t.Errorf("type of %q: got %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestWrongType(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.Set("scalar", "value")
+	s.RPUSH("list", []any{1})
+	s.HSET("hash", "field", 1)
+
+	errs := []error{
+		s.Set("list", "value"),
+		s.Set("hash", "value"),
+		s.HSET("scalar", "field", 1),
+		s.HSET("list", "field", 1),
+		s.LPUSH("scalar", []any{1}),
+		s.RPUSH("hash", []any{1}),
+		s.RADDTOSET("scalar", []any{1}),
+	}
+	_, err = s.LPOP("hash")
+	errs = append(errs, err)
+	_, err = s.LGET("scalar", 0)
+	errs = append(errs, err)
+
+	for i, err := range errs {
+		if !errors.Is(err, ErrWrongType) {
+			t.Errorf("case %d: expected WRONGTYPE, got %v", i, err)
+		}
+	}
+
+	if s.Type("list") != TypeList || s.Type("hash") != TypeHash || s.Type("scalar") != TypeString {
+		t.Errorf("failed writes changed key types")
+	}
+}
+
+func TestDelExists(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.Set("scalar", "value")
+	s.RPUSH("list", []any{1})
+	s.HSET("hash", "field", 1)
+
+	if n := s.Exists("scalar", "list", "hash", "missing", "scalar"); n != 4 {
+		t.Errorf("exists: got %d, want 4", n)
+	}
+
+	if n := s.Del("scalar", "list", "missing"); n != 2 {
+		t.Errorf("del: got %d, want 2", n)
+	}
+
+	if n := s.Exists("scalar", "list", "hash"); n != 1 {
+		t.Errorf("exists after del: got %d, want 1", n)
+	}
+
+	if err := s.LPUSH("scalar", []any{1}); err != nil {
+		t.Errorf("deleted key kept its type: %v", err)
+	}
+}
+
+func TestEmptyListRemoved(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.RPUSH("list", []any{1, 2})
+	s.LPOP("list", 2)
+
+	if s.Exists("list") != 0 {
+		t.Errorf("empty list still exists")
+	}
+	if err := s.Set("list", "value"); err != nil {
+		t.Errorf("set on drained list: %v", err)
+	}
+}
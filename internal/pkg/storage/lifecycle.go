@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// memoryCheckInterval is how often a storage with a memory limit checks the heap.
+const memoryCheckInterval = 100 * time.Millisecond
+
+// heapMetric is the memory taken by live objects and garbage not yet
+// collected, read without stopping the world.
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+var (
+	// ErrClosed is returned by Close when the storage is already closed.
+	ErrClosed = errors.New("storage closed")
+	// ErrOutOfMemory is returned by commands that add data while the heap
+	// is above the limit given by WithMemoryLimit.
+	ErrOutOfMemory = errors.New("OOM command not allowed when used memory > limit")
+)
+
+// lifecycle holds the background work of a storage, which Close stops.
+type lifecycle struct {
+	stop       chan struct{}
+	wg         sync.WaitGroup
+	closed     atomic.Bool
+	overMemory atomic.Bool
+}
+
+// Close stops the background work of the storage: the expiry sweep, the
+// memory checks and the snapshot scheduler, which saves a last snapshot if
+// anything changed since the previous one. It then syncs and closes the
+// append-only file and flushes the logger. It waits for all of it until ctx
+// is done. The storage must not be used once closed.
+func (s *Storage) Close(ctx context.Context) error {
+	if s.tx != nil {
+		return ErrInTransaction
+	}
+	if s.life.closed.Swap(true) {
+		return ErrClosed
+	}
+	close(s.life.stop)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.stopSweeper()
+		s.life.wg.Wait()
+		close(stopped)
+	}()
+
+	var errs []error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err())
+	}
+
+	if err := s.StopSnapshots(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to stop snapshots: %w", err))
+	}
+	if err := s.CloseAOF(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close append-only file: %w", err))
+	}
+
+	s.logger.Info("storage closed")
+	// syncing a logger writing to a terminal fails on some systems, there
+	// is nothing to do about it
+	s.logger.Sync()
+
+	return errors.Join(errs...)
+}
+
+// openPersistence restores the storage from its persistence files and starts
+// logging and saving to them.
+func (s *Storage) openPersistence(o *options) error {
+	if o.aofPath != "" {
+		if err := s.OpenAOF(o.aofPath, o.aofPolicy); err != nil {
+			return err
+		}
+	}
+	if o.snapshots == nil {
+		return nil
+	}
+
+	if o.aofPath == "" {
+		prefix := o.snapshots.Prefix
+		if prefix == "" {
+			prefix = defaultSnapshotPrefix
+		}
+
+		files, err := listSnapshots(o.snapshots.Dir, prefix)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to list snapshots: %w", err)
+		}
+		if len(files) > 0 {
+			if err := s.LoadFromFile(files[len(files)-1]); err != nil {
+				return err
+			}
+			// the storage is what the file holds
+			s.dirty.Store(0)
+		}
+	}
+
+	return s.StartSnapshots(*o.snapshots)
+}
+
+// watchMemory checks the heap against limit until the storage is closed.
+func (s *Storage) watchMemory(limit int64) {
+	defer s.life.wg.Done()
+
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.checkHeap(limit)
+		case <-s.life.stop:
+			return
+		}
+	}
+}
+
+// checkHeap records whether the heap is above limit.
+func (s *Storage) checkHeap(limit int64) {
+	sample := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(sample)
+
+	heap := int64(sample[0].Value.Uint64())
+	over := heap > limit
+	if s.life.overMemory.Swap(over) == over {
+		return
+	}
+
+	if over {
+		s.logger.Warn("memory limit reached, commands adding data are refused",
+			zap.Int64("heap", heap),
+			zap.Int64("limit", limit))
+	} else {
+		s.logger.Info("memory back under the limit",
+			zap.Int64("heap", heap),
+			zap.Int64("limit", limit))
+	}
+}
+
+// checkMemory is called first by commands that add data.
+func (s *Storage) checkMemory() error {
+	if s.life.overMemory.Load() {
+		return ErrOutOfMemory
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestNewStorageOptions(t *testing.T) {
+	// deadlines are kept to the millisecond
+	clock := &fakeClock{now: time.Now().Truncate(time.Millisecond)}
+	s, err := NewStorage(WithShardCount(4), WithLogger(zap.NewNop()), WithClock(clock))
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+	defer s.Close(context.Background())
+
+	if len(s.shards) != 4 {
+		t.Errorf("shards: got %d", len(s.shards))
+	}
+
+	s.SetWithTTL("session", 1, time.Hour)
+	clock.advance(59 * time.Minute)
+	if ttl := s.TTL("session"); ttl != time.Minute {
+		t.Errorf("TTL after 59 minutes: got %v", ttl)
+	}
+	clock.advance(time.Minute)
+	if s.Get("session") != nil {
+		t.Errorf("key outlived its deadline")
+	}
+
+	for _, opts := range [][]Option{
+		{WithShardCount(0)},
+		{WithMemoryLimit(-1)},
+		{WithClock(nil)},
+		{WithAOF(filepath.Join(t.TempDir(), "appendonly.aof"), "sometimes")},
+	} {
+		if _, err := NewStorage(opts...); err == nil {
+			t.Errorf("NewStorage with bad options succeeded")
+		}
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	s, err := NewStorage(WithMemoryLimit(1))
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+	defer s.Close(context.Background())
+
+	s.shard("kept").inner["kept"] = Value{Val: 1, ValueType: kindInt}
+
+	if err := s.Set("a", 1); !errors.Is(err, ErrOutOfMemory) {
+		t.Errorf("Set over the limit: got %v", err)
+	}
+	if _, err := s.ZADD("z", ZMember{"m", 1}); !errors.Is(err, ErrOutOfMemory) {
+		t.Errorf("ZADD over the limit: got %v", err)
+	}
+	err = s.Update(func(tx *Tx) error {
+		return tx.RPUSH("list", []any{1})
+	})
+	if !errors.Is(err, ErrOutOfMemory) {
+		t.Errorf("RPUSH in a transaction over the limit: got %v", err)
+	}
+
+	if s.Get("kept") == nil || s.Del("kept") != 1 {
+		t.Errorf("reads and deletes are refused over the limit")
+	}
+}
+
+func TestCloseSavesSnapshot(t *testing.T) {
+	cfg := SnapshotConfig{Dir: t.TempDir(), Format: FormatBinary, Interval: time.Hour}
+
+	s, err := NewStorage(WithSnapshots(cfg))
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+	s.Set("name", "kv")
+	s.HSET("hash", "field", 1)
+	s.Expire("hash", time.Hour)
+
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := s.Close(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("second Close: got %v", err)
+	}
+
+	reopened, err := NewStorage(WithSnapshots(cfg))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close(context.Background())
+
+	if got := reopened.Get("name"); got == nil || *got != "kv" {
+		t.Errorf("Get name: got %v", got)
+	}
+	if ttl := reopened.TTL("hash"); ttl <= 0 {
+		t.Errorf("TTL hash: got %v", ttl)
+	}
+	if status := reopened.SnapshotStatus(); !status.Running || status.Changes != 0 {
+		t.Errorf("status after reopen: got %+v", status)
+	}
+}
+
+func TestCloseFlushesAOF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+
+	s, err := NewStorage(WithAOF(path, FsyncEverySec))
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+	s.RPUSH("list", []any{1, 2, 3})
+	s.LPOP("list")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if s.AOFStatus().Enabled {
+		t.Errorf("append-only file still open after Close")
+	}
+
+	reopened, err := NewStorage(WithAOF(path, FsyncNo))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close(context.Background())
+
+	if n, _ := reopened.LLEN("list"); n != 2 {
+		t.Errorf("LLEN list: got %d", n)
+	}
+}
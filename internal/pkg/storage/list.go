@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ErrSyntax is returned for malformed command arguments.
+var ErrSyntax = errors.New("syntax error")
+
+// LPosOptions narrows down an LPOS search.
+type LPosOptions struct {
+	// Rank skips the first Rank-1 matches; a negative rank searches from the tail.
+	Rank int
+	// Count is how many matches to return, 0 returns all of them.
+	Count int
+	// MaxLen limits how many elements are compared, 0 compares the whole list.
+	MaxLen int
+}
+
+// LRANGE returns the elements between start and stop inclusive. Negative
+// indexes count from the tail and out of range indexes are clamped.
+func (s *Storage) LRANGE(key string, start, stop int) ([]any, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeList); err != nil {
+		return nil, err
+	}
+
+	list, exist := sh.list[key]
+	if !exist {
+		return []any{}, nil
+	}
+
+	start, stop, ok := listRange(start, stop, len(list.Elem))
+	if !ok {
+		return []any{}, nil
+	}
+
+	res := make([]any, stop-start+1)
+	copy(res, list.Elem[start:stop+1])
+
+	return res, nil
+}
+
+// LLEN returns the length of the list, 0 if it does not exist.
+func (s *Storage) LLEN(key string) (int, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeList); err != nil {
+		return 0, err
+	}
+
+	list, exist := sh.list[key]
+	if !exist {
+		return 0, nil
+	}
+
+	return len(list.Elem), nil
+}
+
+// LINDEX returns the element at index, nil if the index is out of range.
+func (s *Storage) LINDEX(key string, index int) (*any, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeList); err != nil {
+		return nil, err
+	}
+
+	list, exist := sh.list[key]
+	if !exist {
+		return nil, nil
+	}
+
+	index = convertIndex(index, len(list.Elem))
+	if index < 0 || index >= len(list.Elem) {
+		return nil, nil
+	}
+
+	res := list.Elem[index]
+	return &res, nil
+}
+
+// LINSERT puts element "BEFORE" or "AFTER" the first occurrence of pivot and
+// returns the new length, -1 if pivot was not found and 0 if the list does not exist.
+func (s *Storage) LINSERT(key string, where string, pivot any, element any) (int, error) {
+	if err := s.checkMemory(); err != nil {
+		return 0, err
+	}
+
+	var after bool
+	switch strings.ToUpper(where) {
+	case "BEFORE":
+	case "AFTER":
+		after = true
+	default:
+		return 0, ErrSyntax
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeList); err != nil {
+		return 0, err
+	}
+
+	list, exist := sh.list[key]
+	if !exist {
+		return 0, nil
+	}
+
+	for i, elem := range list.Elem {
+		if !sameElem(elem, pivot) {
+			continue
+		}
+
+		if after {
+			i++
+		}
+		list.Elem = append(list.Elem, nil)
+		copy(list.Elem[i+1:], list.Elem[i:])
+		list.Elem[i] = element
+		sh.bumpVersion(key)
+		s.propagate("LINSERT", key, where, pivot, element)
+
+		s.logger.Info("LINSERT executed", zap.String("key", key))
+		return len(list.Elem), nil
+	}
+
+	return -1, nil
+}
+
+// LREM removes occurrences of element and returns how many were removed.
+// A positive count removes that many from the head, a negative one from the
+// tail and 0 removes them all.
+func (s *Storage) LREM(key string, count int, element any) (int, error) {
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeList); err != nil {
+		return 0, err
+	}
+	defer sh.removeIfEmpty(key)
+
+	list, exist := sh.list[key]
+	if !exist {
+		return 0, nil
+	}
+
+	limit := count
+	if limit < 0 {
+		limit = -limit
+		reverse(list.Elem)
+		defer func() { reverse(list.Elem) }()
+	}
+
+	removed := 0
+	kept := list.Elem[:0]
+	for _, elem := range list.Elem {
+		if (limit == 0 || removed < limit) && sameElem(elem, element) {
+			removed++
+			continue
+		}
+		kept = append(kept, elem)
+	}
+	clear(list.Elem[len(kept):])
+	list.Elem = kept
+	if removed > 0 {
+		sh.bumpVersion(key)
+		s.propagate("LREM", key, count, element)
+	}
+
+	s.logger.Info("LREM executed", zap.String("key", key), zap.Int("removed", removed))
+	return removed, nil
+}
+
+// LTRIM keeps only the elements between start and stop inclusive.
+func (s *Storage) LTRIM(key string, start, stop int) error {
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeList); err != nil {
+		return err
+	}
+	defer sh.removeIfEmpty(key)
+
+	list, exist := sh.list[key]
+	if !exist {
+		return nil
+	}
+
+	start, stop, ok := listRange(start, stop, len(list.Elem))
+	if !ok {
+		list.Elem = nil
+		s.propagate("DEL", key)
+		return nil
+	}
+
+	trimmed := make([]any, stop-start+1)
+	copy(trimmed, list.Elem[start:stop+1])
+	list.Elem = trimmed
+	sh.bumpVersion(key)
+	s.propagate("LTRIM", key, start, stop)
+
+	s.logger.Info("LTRIM executed", zap.String("key", key))
+	return nil
+}
+
+// LPOS returns the indexes of the elements equal to element.
+func (s *Storage) LPOS(key string, element any, opts LPosOptions) ([]int, error) {
+	if opts.Count < 0 || opts.MaxLen < 0 {
+		return nil, ErrSyntax
+	}
+
+	rank := opts.Rank
+	if rank == 0 {
+		rank = 1
+	}
+
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeList); err != nil {
+		return nil, err
+	}
+
+	res := []int{}
+	list, exist := sh.list[key]
+	if !exist {
+		return res, nil
+	}
+
+	n := len(list.Elem)
+	skip := rank - 1
+	if rank < 0 {
+		skip = -rank - 1
+	}
+
+	for i := 0; i < n; i++ {
+		if opts.MaxLen > 0 && i >= opts.MaxLen {
+			break
+		}
+
+		idx := i
+		if rank < 0 {
+			idx = n - 1 - i
+		}
+
+		if !sameElem(list.Elem[idx], element) {
+			continue
+		}
+
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		res = append(res, idx)
+		if opts.Count > 0 && len(res) == opts.Count {
+			break
+		}
+	}
+
+	return res, nil
+}
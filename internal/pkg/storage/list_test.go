@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLRANGE(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.RPUSH("list", []any{0, 1, 2, 3, 4, 5})
+
+	cases := []struct {
+		start, stop int
+		expected    []any
+	}{
+		{0, -1, []any{0, 1, 2, 3, 4, 5}},
+		{1, 3, []any{1, 2, 3}},
+		{-2, -1, []any{4, 5}},
+		{-100, 1, []any{0, 1}},
+		{4, 100, []any{4, 5}},
+		{3, 1, []any{}},
+		{10, 20, []any{}},
+	}
+
+	for _, c := range cases {
+		got, err := s.LRANGE("list", c.start, c.stop)
+		if err != nil || !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("LRANGE %d %d: got %v, %v, want %v", c.start, c.stop, got, err, c.expected)
+		}
+	}
+
+	if got, _ := s.LRANGE("missing", 0, -1); len(got) != 0 {
+		t.Errorf("LRANGE on missing key: got %v", got)
+	}
+	if n, _ := s.LLEN("list"); n != 6 {
+		t.Errorf("LLEN: got %d", n)
+	}
+	if v, _ := s.LINDEX("list", -1); v == nil || *v != 5 {
+		t.Errorf("LINDEX -1: got %v", v)
+	}
+	if v, _ := s.LINDEX("list", 6); v != nil {
+		t.Errorf("LINDEX out of range: got %v", *v)
+	}
+}
+
+func TestLINSERT(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.RPUSH("list", []any{"a", "c"})
+
+	if n, _ := s.LINSERT("list", "before", "c", "b"); n != 3 {
+		t.Errorf("LINSERT before: got %d", n)
+	}
+	if n, _ := s.LINSERT("list", "AFTER", "c", "d"); n != 4 {
+		t.Errorf("LINSERT after: got %d", n)
+	}
+	if n, _ := s.LINSERT("list", "after", "x", "y"); n != -1 {
+		t.Errorf("LINSERT missing pivot: got %d", n)
+	}
+	if _, err := s.LINSERT("list", "middle", "a", "b"); err != ErrSyntax {
+		t.Errorf("LINSERT bad position: got %v", err)
+	}
+
+	got, _ := s.LRANGE("list", 0, -1)
+	if !reflect.DeepEqual(got, []any{"a", "b", "c", "d"}) {
+		t.Errorf("LINSERT result: got %v", got)
+	}
+}
+
+func TestLREM(t *testing.T) {
+	cases := []struct {
+		count    int
+		removed  int
+		expected []any
+	}{
+		{0, 3, []any{"b", "c"}},
+		{2, 2, []any{"b", "c", "a"}},
+		{-2, 2, []any{"a", "b", "c"}},
+	}
+
+	for _, c := range cases {
+		s, err := NewStorage()
+		if err != nil {
+			t.Fatalf("no storage: %v", err)
+		}
+		s.RPUSH("list", []any{"a", "b", "a", "c", "a"})
+
+		n, err := s.LREM("list", c.count, "a")
+		got, _ := s.LRANGE("list", 0, -1)
+		if err != nil || n != c.removed || !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("LREM %d: got %d %v, want %d %v", c.count, n, got, c.removed, c.expected)
+		}
+	}
+
+	s, _ := NewStorage()
+	s.RPUSH("nums", []any{1, float64(1)})
+	if n, _ := s.LREM("nums", 0, float64(1)); n != 2 {
+		t.Errorf("LREM does not match 1 with 1.0")
+	}
+	if s.Exists("nums") != 0 {
+		t.Errorf("empty list still exists")
+	}
+}
+
+func TestLTRIM(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.RPUSH("events", []any{1, 2, 3, 4, 5})
+	s.LTRIM("events", -3, -1)
+
+	got, _ := s.LRANGE("events", 0, -1)
+	if !reflect.DeepEqual(got, []any{3, 4, 5}) {
+		t.Errorf("LTRIM: got %v", got)
+	}
+
+	s.LTRIM("events", 5, 10)
+	if s.Exists("events") != 0 {
+		t.Errorf("LTRIM to nothing kept the key")
+	}
+}
+
+func TestLPOS(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.RPUSH("list", []any{"a", "b", "c", "1", "2", "3", "c", "c"})
+
+	cases := []struct {
+		opts     LPosOptions
+		expected []int
+	}{
+		{LPosOptions{Count: 1}, []int{2}},
+		{LPosOptions{}, []int{2, 6, 7}},
+		{LPosOptions{Rank: 2}, []int{6, 7}},
+		{LPosOptions{Rank: -1, Count: 2}, []int{7, 6}},
+		{LPosOptions{MaxLen: 3}, []int{2}},
+		{LPosOptions{Rank: 4}, []int{}},
+	}
+
+	for _, c := range cases {
+		got, err := s.LPOS("list", "c", c.opts)
+		if err != nil || !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("LPOS %+v: got %v, %v, want %v", c.opts, got, err, c.expected)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LMove atomically pops an element from the "LEFT" or "RIGHT" end of src and
+// pushes it to the given end of dst. It returns nil if src is empty.
+func (s *Storage) LMove(src, dst, whereFrom, whereTo string) (*any, error) {
+	fromLeft, toLeft, err := parseMoveSides(whereFrom, whereTo)
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.serveWaiters(dst)
+	defer s.lockKeys(src, dst)()
+
+	if err := s.shard(src).checkType(src, TypeList); err != nil {
+		return nil, err
+	}
+	if err := s.shard(dst).checkType(dst, TypeList); err != nil {
+		return nil, err
+	}
+
+	if _, ok := s.shard(src).list[src]; !ok {
+		return nil, nil
+	}
+
+	val := s.moveElem(src, dst, fromLeft, toLeft)
+	return &val, nil
+}
+
+// RPOPLPUSH moves the tail of src to the head of dst.
+func (s *Storage) RPOPLPUSH(src, dst string) (*any, error) {
+	return s.LMove(src, dst, "RIGHT", "LEFT")
+}
+
+// BLMove is LMove waiting up to timeout for src to receive an element, with
+// the same timeout and cancellation rules as BLPop.
+func (s *Storage) BLMove(ctx context.Context, src, dst, whereFrom, whereTo string, timeout time.Duration) (*any, error) {
+	fromLeft, toLeft, err := parseMoveSides(whereFrom, whereTo)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.blockingPop(ctx, []string{src}, dst, timeout, func(key string) (any, error) {
+		// dst may have changed type while we were waiting
+		if err := s.shard(dst).checkType(dst, TypeList); err != nil {
+			return nil, err
+		}
+
+		return s.moveElem(key, dst, fromLeft, toLeft), nil
+	})
+	if res == nil || err != nil {
+		return nil, err
+	}
+
+	return &res.Value, nil
+}
+
+// BRPOPLPUSH is RPOPLPUSH waiting up to timeout for src to receive an element.
+func (s *Storage) BRPOPLPUSH(ctx context.Context, src, dst string, timeout time.Duration) (*any, error) {
+	return s.BLMove(ctx, src, dst, "RIGHT", "LEFT", timeout)
+}
+
+// moveElem must be called with the shards of src and dst locked, src being a
+// non-empty list and dst a list or a missing key. The caller serves the
+// waiters of dst once the shards are unlocked.
+func (s *Storage) moveElem(src, dst string, fromLeft, toLeft bool) any {
+	val := s.shard(src).popElem(src, fromLeft)
+	s.shard(dst).pushElem(dst, val, toLeft)
+	s.propagate("LMOVE", src, dst, sideName(fromLeft), sideName(toLeft))
+
+	s.logger.Info("LMOVE executed", zap.String("source", src), zap.String("destination", dst))
+	return val
+}
+
+func parseMoveSides(whereFrom, whereTo string) (fromLeft, toLeft bool, err error) {
+	if fromLeft, err = parseSide(whereFrom); err != nil {
+		return false, false, err
+	}
+	if toLeft, err = parseSide(whereTo); err != nil {
+		return false, false, err
+	}
+
+	return fromLeft, toLeft, nil
+}
+
+func sideName(left bool) string {
+	if left {
+		return "LEFT"
+	}
+	return "RIGHT"
+}
+
+func parseSide(where string) (bool, error) {
+	switch strings.ToUpper(where) {
+	case "LEFT":
+		return true, nil
+	case "RIGHT":
+		return false, nil
+	}
+
+	return false, ErrSyntax
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLMove(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.RPUSH("queue", []any{"a", "b", "c"})
+
+	if v, err := s.LMove("queue", "processing", "LEFT", "RIGHT"); err != nil || v == nil || *v != "a" {
+		t.Errorf("LMove: got %v, %v", v, err)
+	}
+	if v, err := s.RPOPLPUSH("queue", "processing"); err != nil || v == nil || *v != "c" {
+		t.Errorf("RPOPLPUSH: got %v, %v", v, err)
+	}
+
+	got, _ := s.LRANGE("processing", 0, -1)
+	if !reflect.DeepEqual(got, []any{"c", "a"}) {
+		t.Errorf("processing list: got %v", got)
+	}
+
+	// rotating a list onto itself
+	s.LMove("processing", "processing", "left", "right")
+	got, _ = s.LRANGE("processing", 0, -1)
+	if !reflect.DeepEqual(got, []any{"a", "c"}) {
+		t.Errorf("rotated list: got %v", got)
+	}
+
+	if v, err := s.LMove("missing", "processing", "LEFT", "LEFT"); v != nil || err != nil {
+		t.Errorf("LMove from missing list: got %v, %v", v, err)
+	}
+	if _, err := s.LMove("queue", "processing", "UP", "LEFT"); err != ErrSyntax {
+		t.Errorf("LMove bad side: got %v", err)
+	}
+
+	s.Set("scalar", 1)
+	if _, err := s.LMove("queue", "scalar", "LEFT", "LEFT"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("LMove to scalar: got %v", err)
+	}
+	if n, _ := s.LLEN("queue"); n != 1 {
+		t.Errorf("failed LMove lost an element")
+	}
+}
+
+func TestBLMove(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.LPUSH("queue", []any{"job"})
+	}()
+
+	v, err := s.BLMove(context.Background(), "queue", "processing", "RIGHT", "LEFT", time.Second)
+	if err != nil || v == nil || *v != "job" {
+		t.Fatalf("BLMove: got %v, %v", v, err)
+	}
+	if n, _ := s.LLEN("processing"); n != 1 {
+		t.Errorf("element did not reach the processing list")
+	}
+	if s.Exists("queue") != 0 {
+		t.Errorf("source list not drained")
+	}
+
+	v, err = s.BRPOPLPUSH(context.Background(), "queue", "processing", 20*time.Millisecond)
+	if v != nil || err != nil {
+		t.Errorf("BRPOPLPUSH timeout: got %v, %v", v, err)
+	}
+}
+
+func TestBLMoveFeedsWaiters(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	done := make(chan *PoppedElem, 1)
+	go func() {
+		res, _ := s.BLPop(context.Background(), []string{"processing"}, time.Second)
+		done <- res
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	s.RPUSH("queue", []any{"job"})
+	s.LMove("queue", "processing", "LEFT", "LEFT")
+
+	if res := <-done; res == nil || res.Value != "job" {
+		t.Errorf("waiter on destination not served: got %+v", res)
+	}
+}
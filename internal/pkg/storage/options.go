@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Option configures a Storage made by NewStorage.
+type Option func(*options)
+
+type options struct {
+	logger      *zap.Logger
+	clock       Clock
+	shardCount  int
+	memoryLimit int64
+	aofPath     string
+	aofPolicy   FsyncPolicy
+	snapshots   *SnapshotConfig
+}
+
+// Clock tells the storage the time key deadlines are measured against.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithLogger has the storage log to logger instead of a new production logger.
+func WithLogger(logger *zap.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithClock has the storage read the time from clock, for tests to move
+// deadlines forward without waiting.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithShardCount splits the keyspace into n shards instead of 16.
+func WithShardCount(n int) Option {
+	return func(o *options) {
+		o.shardCount = n
+	}
+}
+
+// WithMemoryLimit has commands that add data fail with ErrOutOfMemory while
+// the heap of the process is above bytes. Commands that read or remove data
+// keep working, so that clients can make room.
+func WithMemoryLimit(bytes int64) Option {
+	return func(o *options) {
+		o.memoryLimit = bytes
+	}
+}
+
+// WithAOF replays the append-only file at path and logs every change to it,
+// see OpenAOF.
+func WithAOF(path string, policy FsyncPolicy) Option {
+	return func(o *options) {
+		o.aofPath = path
+		o.aofPolicy = policy
+	}
+}
+
+// WithSnapshots saves snapshots as cfg says, see StartSnapshots. Unless the
+// storage also has an append-only file, which holds every change, it starts
+// from the newest snapshot in cfg.Dir.
+func WithSnapshots(cfg SnapshotConfig) Option {
+	return func(o *options) {
+		o.snapshots = &cfg
+	}
+}
+
+func newOptions(opts []Option) (*options, error) {
+	o := &options{
+		clock:      systemClock{},
+		shardCount: defaultShardCount,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.shardCount <= 0 {
+		return nil, errors.New("shard count must be positive")
+	}
+	if o.memoryLimit < 0 {
+		return nil, errors.New("memory limit cannot be negative")
+	}
+	if o.clock == nil {
+		return nil, errors.New("no clock")
+	}
+	if o.logger == nil {
+		logger, err := zap.NewProduction()
+		if err != nil {
+			return nil, err
+		}
+		o.logger = logger
+	}
+
+	return o, nil
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SetOptions makes SetWithOptions conditional or has it keep more of the key.
+type SetOptions struct {
+	// NX stores the value only if the key does not exist, XX only if it does.
+	NX bool
+	XX bool
+	// Get returns the value the key held before.
+	Get bool
+	// KeepTTL keeps the deadline of the key, which Set clears otherwise.
+	KeepTTL bool
+	// TTL gives the key a time to live, 0 leaves it without one.
+	TTL time.Duration
+}
+
+// GetExOptions changes the deadline of the key GetEx reads.
+type GetExOptions struct {
+	// TTL gives the key a new time to live, 0 leaves its deadline alone.
+	TTL time.Duration
+	// Persist removes the deadline of the key.
+	Persist bool
+}
+
+// SetWithOptions is Set following opts. It reports whether the value was
+// stored and, if opts.Get is set, returns the previous value, nil if the key
+// did not exist. NX and XX together, or KeepTTL with a TTL, are ErrSyntax.
+func (s *Storage) SetWithOptions(key string, value any, opts SetOptions) (*any, bool, error) {
+	if err := s.checkMemory(); err != nil {
+		return nil, false, err
+	}
+
+	if (opts.NX && opts.XX) || (opts.KeepTTL && opts.TTL != 0) || opts.TTL < 0 {
+		return nil, false, ErrSyntax
+	}
+
+	val, err := newValue(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return nil, false, err
+	}
+
+	var old *any
+	prev, exists := sh.inner[key]
+	if exists && opts.Get {
+		old = &prev.Val
+	}
+
+	if (opts.NX && exists) || (opts.XX && !exists) {
+		return old, false, nil
+	}
+
+	sh.inner[key] = val
+	switch {
+	case opts.TTL > 0:
+		sh.innerExpire[key] = s.clock.Now().Add(opts.TTL).UnixMilli()
+	case !opts.KeepTTL:
+		delete(sh.innerExpire, key)
+	}
+	sh.bumpVersion(key)
+	s.propagateSet(sh, key)
+
+	s.logger.Info("value set with options",
+		zap.String("key", key),
+		zap.String("value", string(val.ValueType)),
+		zap.Bool("nx", opts.NX),
+		zap.Bool("xx", opts.XX))
+	return old, true, nil
+}
+
+// GetDel returns the value stored at key and deletes the key, nil if it
+// does not exist.
+func (s *Storage) GetDel(key string) (*any, error) {
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return nil, err
+	}
+
+	result, ok := sh.inner[key]
+	if !ok {
+		return nil, nil
+	}
+	sh.removeKey(key)
+	s.propagate("DEL", key)
+
+	s.logger.Info("GETDEL executed", zap.String("key", key))
+	return &result.Val, nil
+}
+
+// GetEx returns the value stored at key, nil if it does not exist, and
+// changes its deadline following opts. TTL and Persist together are ErrSyntax.
+func (s *Storage) GetEx(key string, opts GetExOptions) (*any, error) {
+	if (opts.Persist && opts.TTL != 0) || opts.TTL < 0 {
+		return nil, ErrSyntax
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return nil, err
+	}
+
+	result, ok := sh.inner[key]
+	if !ok {
+		return nil, nil
+	}
+
+	switch {
+	case opts.TTL > 0:
+		sh.innerExpire[key] = s.clock.Now().Add(opts.TTL).UnixMilli()
+		sh.bumpVersion(key)
+		s.propagate("PEXPIREAT", key, sh.innerExpire[key])
+	case opts.Persist:
+		if _, ok := sh.innerExpire[key]; ok {
+			delete(sh.innerExpire, key)
+			sh.bumpVersion(key)
+			s.propagate("PERSIST", key)
+		}
+	}
+
+	s.logger.Info("GETEX executed", zap.String("key", key))
+	return &result.Val, nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetWithOptions(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	if _, ok, err := s.SetWithOptions("lock", "owner-1", SetOptions{NX: true, TTL: time.Minute}); err != nil || !ok {
+		t.Fatalf("NX on a missing key: got %v, %v", ok, err)
+	}
+	if _, ok, _ := s.SetWithOptions("lock", "owner-2", SetOptions{NX: true}); ok {
+		t.Errorf("NX on an existing key stored the value")
+	}
+	if ttl := s.TTL("lock"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("TTL after SET with a ttl: got %v", ttl)
+	}
+
+	if _, ok, _ := s.SetWithOptions("missing", 1, SetOptions{XX: true}); ok || s.Exists("missing") != 0 {
+		t.Errorf("XX on a missing key created it")
+	}
+
+	old, ok, err := s.SetWithOptions("lock", "owner-3", SetOptions{XX: true, Get: true, KeepTTL: true})
+	if err != nil || !ok || old == nil || *old != "owner-1" {
+		t.Errorf("XX GET: got %v, %v, %v", old, ok, err)
+	}
+	if ttl := s.TTL("lock"); ttl <= 0 {
+		t.Errorf("KEEPTTL lost the deadline: %v", ttl)
+	}
+
+	// NX with GET still returns the value it did not replace
+	old, ok, _ = s.SetWithOptions("lock", "owner-4", SetOptions{NX: true, Get: true})
+	if ok || old == nil || *old != "owner-3" {
+		t.Errorf("NX GET on an existing key: got %v, %v", old, ok)
+	}
+
+	if _, _, err := s.SetWithOptions("lock", "x", SetOptions{}); err != nil || s.TTL("lock") != TTLNoExpire {
+		t.Errorf("plain SetWithOptions kept the deadline: %v", err)
+	}
+
+	for _, opts := range []SetOptions{
+		{NX: true, XX: true},
+		{KeepTTL: true, TTL: time.Second},
+		{TTL: -time.Second},
+	} {
+		if _, _, err := s.SetWithOptions("lock", "x", opts); !errors.Is(err, ErrSyntax) {
+			t.Errorf("SetWithOptions(%+v): got %v", opts, err)
+		}
+	}
+
+	s.RPUSH("list", []any{1})
+	if _, _, err := s.SetWithOptions("list", "x", SetOptions{Get: true}); !errors.Is(err, ErrWrongType) {
+		t.Errorf("SetWithOptions on a list: got %v", err)
+	}
+}
+
+func TestGetDel(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.Set("token", "abc")
+	if val, err := s.GetDel("token"); err != nil || val == nil || *val != "abc" {
+		t.Errorf("GetDel: got %v, %v", val, err)
+	}
+	if val, err := s.GetDel("token"); err != nil || val != nil {
+		t.Errorf("GetDel twice: got %v, %v", val, err)
+	}
+
+	s.SADD("set", "a")
+	if _, err := s.GetDel("set"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("GetDel on a set: got %v", err)
+	}
+}
+
+func TestGetEx(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.Set("session", "data")
+	if val, err := s.GetEx("session", GetExOptions{TTL: time.Minute}); err != nil || *val != "data" {
+		t.Errorf("GetEx with a ttl: got %v, %v", val, err)
+	}
+	if ttl := s.TTL("session"); ttl <= 0 {
+		t.Errorf("GetEx did not set the deadline: %v", ttl)
+	}
+
+	s.GetEx("session", GetExOptions{})
+	if ttl := s.TTL("session"); ttl <= 0 {
+		t.Errorf("GetEx without options changed the deadline: %v", ttl)
+	}
+
+	s.GetEx("session", GetExOptions{Persist: true})
+	if ttl := s.TTL("session"); ttl != TTLNoExpire {
+		t.Errorf("GetEx PERSIST kept the deadline: %v", ttl)
+	}
+
+	if val, err := s.GetEx("missing", GetExOptions{TTL: time.Minute}); err != nil || val != nil {
+		t.Errorf("GetEx on a missing key: got %v, %v", val, err)
+	}
+	if _, err := s.GetEx("session", GetExOptions{TTL: time.Minute, Persist: true}); !errors.Is(err, ErrSyntax) {
+		t.Errorf("GetEx with TTL and PERSIST: got %v", err)
+	}
+}
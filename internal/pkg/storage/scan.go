@@ -0,0 +1,387 @@
+package storage
+
+import (
+	"container/heap"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// defaultScanCount is the page size used when Scan is given a count below one.
+const defaultScanCount = 10
+
+// ErrInvalidCursor is returned for a cursor that was not produced by a scan.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Keys returns every live key matching the glob pattern in sorted order.
+// Patterns support "*", "?", "[abc]", "[^a-z]" and "\" escapes.
+//
+// Keys walks the whole keyspace in one call, prefer Scan on large stores.
+func (s *Storage) Keys(pattern string) []string {
+	res := []string{}
+	s.eachKey(func(key string, _ KeyType) {
+		if globMatch(pattern, key) {
+			res = append(res, key)
+		}
+	})
+	sort.Strings(res)
+
+	return res
+}
+
+// Scan returns a page of up to count keys matching the glob pattern match
+// and, unless typ is empty, holding a value of that type. Pass an empty cursor
+// to start and the returned one to continue, the scan is over when the
+// returned cursor is empty.
+//
+// Keys come in sorted order and the cursor remembers the last one returned,
+// so a key that exists during the whole scan is returned exactly once however
+// the keyspace changes in between calls.
+//
+// There is no sorted index behind the cursor, so every page visits every key
+// of the storage to find the count smallest names after it. A page costs
+// O(N log count) for N keys and a full walk O(N²/count): Scan bounds how much
+// one call returns and how long it holds a shard, not the work it does.
+// Raise count to walk a large keyspace in fewer passes.
+func (s *Storage) Scan(cursor, match string, count int, typ KeyType) ([]string, string, error) {
+	p, err := newPager(cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.eachKey(func(key string, keyType KeyType) {
+		if globMatch(match, key) && (typ == "" || keyType == typ) {
+			p.offer(key)
+		}
+	})
+
+	keys, next := p.page()
+	return keys, next, nil
+}
+
+// HScan is Scan over the fields of the hash at key. It returns the fields of
+// the page with their values.
+func (s *Storage) HScan(key, cursor, match string, count int) (map[string]any, string, error) {
+	p, err := newPager(cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeHash); err != nil {
+		return nil, "", err
+	}
+
+	hash := sh.innerMap[key]
+	for field := range hash {
+		if globMatch(match, field) {
+			p.offer(field)
+		}
+	}
+
+	fields, next := p.page()
+	res := make(map[string]any, len(fields))
+	for _, field := range fields {
+		res[field] = hash[field].Val
+	}
+
+	return res, next, nil
+}
+
+// SScan is Scan over the members of the set at key.
+func (s *Storage) SScan(key, cursor, match string, count int) ([]string, string, error) {
+	p, err := newPager(cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeSet); err != nil {
+		return nil, "", err
+	}
+
+	for member := range sh.sets[key] {
+		if globMatch(match, member) {
+			p.offer(member)
+		}
+	}
+
+	members, next := p.page()
+	return members, next, nil
+}
+
+// ZScan is Scan over the members of the sorted set at key. Members come in
+// member order, not score order.
+func (s *Storage) ZScan(key, cursor, match string, count int) ([]ZMember, string, error) {
+	p, err := newPager(cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return nil, "", err
+	}
+
+	zs, ok := sh.zsets[key]
+	if !ok {
+		return []ZMember{}, "", nil
+	}
+
+	for member := range zs.dict {
+		if globMatch(match, member) {
+			p.offer(member)
+		}
+	}
+
+	members, next := p.page()
+	res := make([]ZMember, len(members))
+	for i, member := range members {
+		res[i] = ZMember{Member: member, Score: zs.dict[member]}
+	}
+
+	return res, next, nil
+}
+
+// LScan pages through the list at key from head to tail, returning up to
+// count elements whose text form matches match. The cursor is the index to
+// resume from, so pushing to the head of the list while scanning shifts it.
+func (s *Storage) LScan(key, cursor, match string, count int) ([]any, string, error) {
+	start := 0
+	if cursor != "" {
+		var err error
+		if start, err = strconv.Atoi(cursor); err != nil || start < 0 {
+			return nil, "", ErrInvalidCursor
+		}
+	}
+	if count < 1 {
+		count = defaultScanCount
+	}
+
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeList); err != nil {
+		return nil, "", err
+	}
+
+	res := []any{}
+	list, ok := sh.list[key]
+	if !ok {
+		return res, "", nil
+	}
+
+	i := start
+	for ; i < len(list.Elem) && len(res) < count; i++ {
+		if globMatch(match, fmt.Sprint(list.Elem[i])) {
+			res = append(res, list.Elem[i])
+		}
+	}
+
+	if i >= len(list.Elem) {
+		return res, "", nil
+	}
+	return res, strconv.Itoa(i), nil
+}
+
+// eachKey calls f with every live key and its type. Shards are read-locked
+// one at a time, so that walking the keyspace never stops it as a whole.
+func (s *Storage) eachKey(f func(key string, typ KeyType)) {
+	for _, sh := range s.shards {
+		unlock := s.rlockShard(sh)
+		sh.eachKey(f)
+		unlock()
+	}
+}
+
+// eachKey must be called with sh.mu held, at least for reading.
+func (sh *shard) eachKey(f func(key string, typ KeyType)) {
+	visit := func(key string, typ KeyType) {
+		if !sh.isExpired(key) {
+			f(key, typ)
+		}
+	}
+
+	for key := range sh.inner {
+		visit(key, TypeString)
+	}
+	for key := range sh.list {
+		visit(key, TypeList)
+	}
+	for key := range sh.innerMap {
+		visit(key, TypeHash)
+	}
+	for key := range sh.sets {
+		visit(key, TypeSet)
+	}
+	for key := range sh.zsets {
+		visit(key, TypeZSet)
+	}
+}
+
+// pager keeps the count smallest names above the cursor in a max-heap, so a
+// page costs one pass over the candidates without sorting all of them.
+type pager struct {
+	after  string
+	resume bool
+	count  int
+	names  maxHeap
+	more   bool
+}
+
+func newPager(cursor string, count int) (*pager, error) {
+	if count < 1 {
+		count = defaultScanCount
+	}
+
+	p := &pager{count: count, resume: cursor != ""}
+	if cursor != "" {
+		after, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		p.after = string(after)
+	}
+
+	return p, nil
+}
+
+func (p *pager) offer(name string) {
+	if p.resume && name <= p.after {
+		return
+	}
+
+	if len(p.names) < p.count {
+		heap.Push(&p.names, name)
+		return
+	}
+
+	p.more = true
+	if name < p.names[0] {
+		p.names[0] = name
+		heap.Fix(&p.names, 0)
+	}
+}
+
+// page returns the names in order and the cursor for the next page.
+func (p *pager) page() ([]string, string) {
+	names := []string(p.names)
+	sort.Strings(names)
+
+	if !p.more {
+		return names, ""
+	}
+	return names, base64.RawURLEncoding.EncodeToString([]byte(names[len(names)-1]))
+}
+
+type maxHeap []string
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i] > h[j] }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(string)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// globMatch reports whether name matches a glob pattern. An empty pattern
+// matches everything.
+func globMatch(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if globMatch(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if name == "" {
+				return false
+			}
+			pattern, name = pattern[1:], name[1:]
+		case '[':
+			if name == "" {
+				return false
+			}
+			rest, ok := matchClass(pattern[1:], name[0])
+			if !ok {
+				return false
+			}
+			pattern, name = rest, name[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if name == "" || pattern[0] != name[0] {
+				return false
+			}
+			pattern, name = pattern[1:], name[1:]
+		}
+	}
+
+	return name == ""
+}
+
+// matchClass matches c against the class at the start of pattern, just after
+// "[", and returns the pattern left after the closing "]".
+func matchClass(pattern string, c byte) (string, bool) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		lo := pattern[0]
+		if lo == '\\' && len(pattern) > 1 {
+			pattern = pattern[1:]
+			lo = pattern[0]
+		}
+		pattern = pattern[1:]
+
+		hi := lo
+		if len(pattern) > 1 && pattern[0] == '-' && pattern[1] != ']' {
+			hi = pattern[1]
+			pattern = pattern[2:]
+		}
+
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if c >= lo && c <= hi {
+			matched = true
+		}
+	}
+
+	if pattern == "" {
+		// unterminated class, no name can match it
+		return "", false
+	}
+
+	return pattern[1:], matched != negate
+}
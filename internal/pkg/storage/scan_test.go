@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"", "anything", true},
+		{"*", "", true},
+		{"session:*", "session:42", true},
+		{"session:*", "sessions", false},
+		{"*:42", "user/session:42", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"h[ab", "ha", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+	}
+
+	for _, c := range cases {
+		if got := globMatch(c.pattern, c.name); got != c.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	s, _ := NewStorage()
+	s.Set("session:1", "a")
+	s.Set("session:2", "b")
+	s.Set("user:1", "c")
+	s.LPUSH("session:list", []any{1})
+	s.SetWithTTL("session:old", "d", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	want := []string{"session:1", "session:2", "session:list"}
+	if got := s.Keys("session:*"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys = %v, want %v", got, want)
+	}
+}
+
+func TestScan(t *testing.T) {
+	s, _ := NewStorage()
+	for i := 0; i < 25; i++ {
+		s.Set(fmt.Sprintf("session:%02d", i), i)
+	}
+	s.SADD("session:set", "x")
+	s.Set("other", 1)
+
+	var got []string
+	cursor, pages := "", 0
+	for {
+		keys, next, err := s.Scan(cursor, "session:*", 10, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, keys...)
+		pages++
+
+		// keys added or removed during the scan do not disturb the others
+		if pages == 1 {
+			s.Set("session:aa", 1)
+			s.Del("session:00")
+		}
+
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if len(got) != 27 || pages != 3 {
+		t.Errorf("scan returned %d keys in %d pages, want 27 in 3", len(got), pages)
+	}
+	if !sort.StringsAreSorted(got) {
+		t.Errorf("scan keys are not in order: %v", got)
+	}
+
+	keys, next, _ := s.Scan("", "*", 100, TypeSet)
+	if !reflect.DeepEqual(keys, []string{"session:set"}) || next != "" {
+		t.Errorf("type filtered scan = %v, %q", keys, next)
+	}
+
+	if _, _, err := s.Scan("!!", "", 10, ""); err != ErrInvalidCursor {
+		t.Errorf("bad cursor error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestScanContainers(t *testing.T) {
+	s, _ := NewStorage()
+	s.HSET("hash", "f1", 1)
+	s.HSET("hash", "f2", 2)
+	s.HSET("hash", "g1", 3)
+	s.SADD("set", "a", "b", "c")
+	s.ZADD("zset", ZMember{"a", 3}, ZMember{"b", 1})
+	s.RPUSH("list", []any{"a1", "b1", "a2", "a3"})
+
+	fields, next, err := s.HScan("hash", "", "f*", 1)
+	if err != nil || len(fields) != 1 || fields["f1"] != 1 || next == "" {
+		t.Errorf("HScan first page = %v, %q, %v", fields, next, err)
+	}
+	fields, next, _ = s.HScan("hash", next, "f*", 1)
+	if len(fields) != 1 || fields["f2"] != 2 || next != "" {
+		t.Errorf("HScan second page = %v, %q", fields, next)
+	}
+
+	members, _, _ := s.SScan("set", "", "", 10)
+	if !reflect.DeepEqual(members, []string{"a", "b", "c"}) {
+		t.Errorf("SScan = %v", members)
+	}
+
+	zmembers, _, _ := s.ZScan("zset", "", "", 10)
+	if want := []ZMember{{"a", 3}, {"b", 1}}; !reflect.DeepEqual(zmembers, want) {
+		t.Errorf("ZScan = %v, want %v", zmembers, want)
+	}
+
+	elems, next, _ := s.LScan("list", "", "a*", 2)
+	if !reflect.DeepEqual(elems, []any{"a1", "a2"}) || next != "3" {
+		t.Errorf("LScan first page = %v, %q", elems, next)
+	}
+	elems, next, _ = s.LScan("list", next, "a*", 2)
+	if !reflect.DeepEqual(elems, []any{"a3"}) || next != "" {
+		t.Errorf("LScan second page = %v, %q", elems, next)
+	}
+
+	if _, _, err := s.SScan("hash", "", "", 10); !errors.Is(err, ErrWrongType) {
+		t.Errorf("SScan on a hash error = %v, want ErrWrongType", err)
+	}
+}
@@ -0,0 +1,336 @@
+package storage
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+type setOp int
+
+const (
+	setInter setOp = iota
+	setUnion
+	setDiff
+)
+
+// SADD adds members to the set and returns how many were not there yet.
+func (s *Storage) SADD(key string, members ...string) (int, error) {
+	if err := s.checkMemory(); err != nil {
+		return 0, err
+	}
+
+	if len(members) == 0 {
+		return 0, errors.New("WrongArgs")
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeSet); err != nil {
+		return 0, err
+	}
+
+	set, ok := sh.sets[key]
+	if !ok {
+		set = make(map[string]struct{}, len(members))
+		sh.sets[key] = set
+	}
+
+	added := 0
+	for _, member := range members {
+		if _, ok := set[member]; !ok {
+			set[member] = struct{}{}
+			added++
+		}
+	}
+	if added > 0 {
+		sh.bumpVersion(key)
+		s.propagate(append([]any{"SADD", key}, stringArgs(members)...)...)
+	}
+
+	s.logger.Info("SADD executed", zap.String("key", key), zap.Int("added", added))
+	return added, nil
+}
+
+// SREM removes members from the set and returns how many were there.
+func (s *Storage) SREM(key string, members ...string) (int, error) {
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeSet); err != nil {
+		return 0, err
+	}
+	defer sh.removeIfEmpty(key)
+
+	set := sh.sets[key]
+	removed := 0
+	for _, member := range members {
+		if _, ok := set[member]; ok {
+			delete(set, member)
+			removed++
+		}
+	}
+	if removed > 0 {
+		sh.bumpVersion(key)
+		s.propagate(append([]any{"SREM", key}, stringArgs(members)...)...)
+	}
+
+	s.logger.Info("SREM executed", zap.String("key", key), zap.Int("removed", removed))
+	return removed, nil
+}
+
+// SISMEMBER reports whether member belongs to the set.
+func (s *Storage) SISMEMBER(key string, member string) (bool, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeSet); err != nil {
+		return false, err
+	}
+
+	_, ok := sh.sets[key][member]
+	return ok, nil
+}
+
+// SMEMBERS returns the members of the set in sorted order.
+func (s *Storage) SMEMBERS(key string) ([]string, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeSet); err != nil {
+		return nil, err
+	}
+
+	return sortedMembers(sh.sets[key]), nil
+}
+
+// SCARD returns the number of members in the set.
+func (s *Storage) SCARD(key string) (int, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeSet); err != nil {
+		return 0, err
+	}
+
+	return len(sh.sets[key]), nil
+}
+
+// SRANDMEMBER returns random members without removing them. A positive count
+// returns up to count distinct members, a negative one returns exactly -count
+// members that may repeat.
+func (s *Storage) SRANDMEMBER(key string, count int) ([]string, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeSet); err != nil {
+		return nil, err
+	}
+
+	members := sortedMembers(sh.sets[key])
+	if len(members) == 0 || count == 0 {
+		return []string{}, nil
+	}
+
+	if count < 0 {
+		res := make([]string, -count)
+		for i := range res {
+			res[i] = members[rand.Intn(len(members))]
+		}
+		return res, nil
+	}
+
+	rand.Shuffle(len(members), func(i, j int) {
+		members[i], members[j] = members[j], members[i]
+	})
+	if count < len(members) {
+		members = members[:count]
+	}
+
+	return members, nil
+}
+
+// SPOP removes and returns up to count random members.
+func (s *Storage) SPOP(key string, count int) ([]string, error) {
+	if count < 0 {
+		return nil, errors.New("count must be positive")
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeSet); err != nil {
+		return nil, err
+	}
+	defer sh.removeIfEmpty(key)
+
+	set := sh.sets[key]
+	res := make([]string, 0, count)
+	// map iteration order is random enough to pick members
+	for member := range set {
+		if len(res) == count {
+			break
+		}
+		res = append(res, member)
+		delete(set, member)
+	}
+	if len(res) > 0 {
+		sh.bumpVersion(key)
+		s.propagate(append([]any{"SREM", key}, stringArgs(res)...)...)
+	}
+
+	s.logger.Info("SPOP executed", zap.String("key", key), zap.Int("popped", len(res)))
+	return res, nil
+}
+
+// SINTER returns the members present in every given set.
+func (s *Storage) SINTER(keys ...string) ([]string, error) {
+	return s.setAlgebra(setInter, keys)
+}
+
+// SUNION returns the members present in any of the given sets.
+func (s *Storage) SUNION(keys ...string) ([]string, error) {
+	return s.setAlgebra(setUnion, keys)
+}
+
+// SDIFF returns the members of the first set that are in none of the others.
+func (s *Storage) SDIFF(keys ...string) ([]string, error) {
+	return s.setAlgebra(setDiff, keys)
+}
+
+// SINTERSTORE stores the result of SINTER at dst, replacing what dst
+// holds, and returns its size.
+func (s *Storage) SINTERSTORE(dst string, keys ...string) (int, error) {
+	return s.setAlgebraStore(setInter, dst, keys)
+}
+
+// SUNIONSTORE stores the result of SUNION at dst, replacing what dst
+// holds, and returns its size.
+func (s *Storage) SUNIONSTORE(dst string, keys ...string) (int, error) {
+	return s.setAlgebraStore(setUnion, dst, keys)
+}
+
+// SDIFFSTORE stores the result of SDIFF at dst, replacing what dst
+// holds, and returns its size.
+func (s *Storage) SDIFFSTORE(dst string, keys ...string) (int, error) {
+	return s.setAlgebraStore(setDiff, dst, keys)
+}
+
+func (s *Storage) setAlgebra(op setOp, keys []string) ([]string, error) {
+	defer s.rlockKeys(keys...)()
+
+	res, err := s.combineSets(op, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return sortedMembers(res), nil
+}
+
+// storeCommand is the name the append-only file logs a store of op under.
+func (op setOp) storeCommand() string {
+	switch op {
+	case setInter:
+		return "SINTERSTORE"
+	case setUnion:
+		return "SUNIONSTORE"
+	}
+	return "SDIFFSTORE"
+}
+
+func (s *Storage) setAlgebraStore(op setOp, dst string, keys []string) (int, error) {
+	if err := s.checkMemory(); err != nil {
+		return 0, err
+	}
+
+	defer s.lockKeys(append([]string{dst}, keys...)...)()
+
+	res, err := s.combineSets(op, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	// dst is overwritten whatever it holds
+	sh := s.shard(dst)
+	sh.removeKey(dst)
+	if len(res) > 0 {
+		sh.sets[dst] = res
+	}
+	sh.bumpVersion(dst)
+	s.propagate(append([]any{op.storeCommand(), dst}, stringArgs(keys)...)...)
+
+	s.logger.Info("set stored", zap.String("key", dst), zap.Int("members", len(res)))
+	return len(res), nil
+}
+
+// combineSets builds a new set out of the sets at keys. Missing keys count as
+// empty sets. It must be called with the shards of keys locked.
+func (s *Storage) combineSets(op setOp, keys []string) (map[string]struct{}, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("WrongArgs")
+	}
+
+	for _, key := range keys {
+		if err := s.shard(key).checkType(key, TypeSet); err != nil {
+			return nil, err
+		}
+	}
+
+	res := make(map[string]struct{})
+	if op == setInter {
+		// start from the smallest set, nothing outside it can be in the result
+		smallest := keys[0]
+		for _, key := range keys[1:] {
+			if len(s.setAt(key)) < len(s.setAt(smallest)) {
+				smallest = key
+			}
+		}
+
+	members:
+		for member := range s.setAt(smallest) {
+			for _, key := range keys {
+				if _, ok := s.setAt(key)[member]; !ok {
+					continue members
+				}
+			}
+			res[member] = struct{}{}
+		}
+
+		return res, nil
+	}
+
+	for member := range s.setAt(keys[0]) {
+		res[member] = struct{}{}
+	}
+
+	for _, key := range keys[1:] {
+		for member := range s.setAt(key) {
+			if op == setUnion {
+				res[member] = struct{}{}
+			} else {
+				delete(res, member)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// setAt returns the set at key, nil if there is none. It must be called with
+// the shard of key locked.
+func (s *Storage) setAt(key string) map[string]struct{} {
+	return s.shard(key).sets[key]
+}
+
+func sortedMembers(set map[string]struct{}) []string {
+	res := make([]string, 0, len(set))
+	for member := range set {
+		res = append(res, member)
+	}
+	sort.Strings(res)
+
+	return res
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetCommands(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	if n, _ := s.SADD("tags", "go", "db", "go"); n != 2 {
+		t.Errorf("SADD: got %d", n)
+	}
+	if n, _ := s.SADD("tags", "go", "cache"); n != 1 {
+		t.Errorf("SADD existing: got %d", n)
+	}
+	if ok, _ := s.SISMEMBER("tags", "db"); !ok {
+		t.Errorf("SISMEMBER: member missing")
+	}
+	if n, _ := s.SCARD("tags"); n != 3 {
+		t.Errorf("SCARD: got %d", n)
+	}
+
+	members, _ := s.SMEMBERS("tags")
+	if !reflect.DeepEqual(members, []string{"cache", "db", "go"}) {
+		t.Errorf("SMEMBERS: got %v", members)
+	}
+
+	if n, _ := s.SREM("tags", "db", "missing"); n != 1 {
+		t.Errorf("SREM: got %d", n)
+	}
+
+	if got, _ := s.SRANDMEMBER("tags", 5); len(got) != 2 {
+		t.Errorf("SRANDMEMBER positive count: got %v", got)
+	}
+	if got, _ := s.SRANDMEMBER("tags", -5); len(got) != 5 {
+		t.Errorf("SRANDMEMBER negative count: got %v", got)
+	}
+
+	popped, _ := s.SPOP("tags", 5)
+	if len(popped) != 2 || s.Exists("tags") != 0 {
+		t.Errorf("SPOP: got %v, key left behind %v", popped, s.Exists("tags"))
+	}
+
+	s.Set("scalar", 1)
+	if _, err := s.SADD("scalar", "a"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("SADD on scalar: got %v", err)
+	}
+	if s.Type("scalar") != TypeString {
+		t.Errorf("scalar type changed")
+	}
+}
+
+func TestSetAlgebra(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.SADD("doc1", "a", "b", "c", "d")
+	s.SADD("doc2", "c", "d", "e")
+	s.SADD("doc3", "a", "c", "e")
+
+	inter, _ := s.SINTER("doc1", "doc2", "doc3")
+	if !reflect.DeepEqual(inter, []string{"c"}) {
+		t.Errorf("SINTER: got %v", inter)
+	}
+
+	union, _ := s.SUNION("doc1", "doc2", "missing")
+	if !reflect.DeepEqual(union, []string{"a", "b", "c", "d", "e"}) {
+		t.Errorf("SUNION: got %v", union)
+	}
+
+	diff, _ := s.SDIFF("doc1", "doc2", "doc3")
+	if !reflect.DeepEqual(diff, []string{"b"}) {
+		t.Errorf("SDIFF: got %v", diff)
+	}
+
+	if got, _ := s.SINTER("doc1", "missing"); len(got) != 0 {
+		t.Errorf("SINTER with missing key: got %v", got)
+	}
+
+	if n, _ := s.SINTERSTORE("doc1", "doc1", "doc2"); n != 2 {
+		t.Errorf("SINTERSTORE onto a source: got %d", n)
+	}
+	members, _ := s.SMEMBERS("doc1")
+	if !reflect.DeepEqual(members, []string{"c", "d"}) {
+		t.Errorf("SINTERSTORE result: got %v", members)
+	}
+
+	if n, _ := s.SDIFFSTORE("empty", "doc2", "doc2"); n != 0 || s.Exists("empty") != 0 {
+		t.Errorf("SDIFFSTORE with empty result created the key")
+	}
+	if n, _ := s.SUNIONSTORE("all", "doc2", "doc3"); n != 4 {
+		t.Errorf("SUNIONSTORE: got %d", n)
+	}
+
+	s.RPUSH("list", []any{"a"})
+	if _, err := s.SUNION("doc2", "list"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("SUNION with a list: got %v", err)
+	}
+	if n, err := s.SUNIONSTORE("list", "doc2"); err != nil || n != 3 || s.Type("list") != TypeSet {
+		t.Errorf("SUNIONSTORE onto a list: got %d, %v, type %s", n, err, s.Type("list"))
+	}
+
+	s.HSET("hash", "f", 1)
+	s.Expire("hash", time.Hour)
+	if n, err := s.SINTERSTORE("hash", "doc2", "doc3"); err != nil || n != 2 || s.TTL("hash") != TTLNoExpire {
+		t.Errorf("SINTERSTORE onto a hash: got %d, %v, TTL %v", n, err, s.TTL("hash"))
+	}
+	s.Set("string", "a")
+	if _, err := s.SDIFFSTORE("doc2", "string"); !errors.Is(err, ErrWrongType) || s.Type("doc2") != TypeSet {
+		t.Errorf("SDIFFSTORE from a string: got %v", err)
+	}
+}
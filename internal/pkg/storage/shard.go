@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// defaultShardCount is the number of shards of a new Storage.
+const defaultShardCount = 16
+
+// shard holds the keys hashing to it with their deadlines. Commands lock the
+// shards of the keys they touch, so commands on different shards run in
+// parallel and reads on the same shard share its lock. Commands go through
+// the lock helpers of Storage, which know when a transaction already holds
+// every shard.
+type shard struct {
+	mu          sync.RWMutex
+	inner       map[string]Value
+	list        map[string]*List
+	innerMap    map[string]map[string]Value
+	sets        map[string]map[string]struct{}
+	zsets       map[string]*zset
+	innerExpire map[string]int64
+	// versions holds the version of every key, taken from revision, which
+	// grows by one on each change to a key of the shard.
+	versions map[string]uint64
+	revision uint64
+	// view is set while a snapshot is being written, see beginView.
+	view   *shardView
+	clock  Clock
+	logger *zap.Logger
+}
+
+func newShard(logger *zap.Logger, clock Clock) *shard {
+	return &shard{
+		inner:       make(map[string]Value),
+		list:        make(map[string]*List),
+		innerMap:    make(map[string]map[string]Value),
+		sets:        make(map[string]map[string]struct{}),
+		zsets:       make(map[string]*zset),
+		innerExpire: make(map[string]int64),
+		versions:    make(map[string]uint64),
+		clock:       clock,
+		logger:      logger,
+	}
+}
+
+// lock write-locks the shard, drops the given keys if they have expired and
+// preserves them for the snapshot being written, if any.
+func (sh *shard) lock(keys ...string) {
+	sh.mu.Lock()
+	for _, key := range keys {
+		sh.expireIfNeeded(key)
+		sh.preserve(key)
+	}
+}
+
+// rlock read-locks the shard once none of the given keys is past its deadline.
+// Expired keys are dropped under a short write lock first, so that readers
+// never have to delete anything themselves.
+func (sh *shard) rlock(keys ...string) {
+	for {
+		sh.mu.RLock()
+
+		expired := false
+		for _, key := range keys {
+			if sh.isExpired(key) {
+				expired = true
+				break
+			}
+		}
+		if !expired {
+			return
+		}
+		sh.mu.RUnlock()
+
+		sh.lock(keys...)
+		sh.mu.Unlock()
+	}
+}
+
+// isExpired reports whether the key is past its deadline. It must be called
+// with sh.mu held, at least for reading.
+func (sh *shard) isExpired(key string) bool {
+	at, ok := sh.innerExpire[key]
+	return ok && sh.clock.Now().UnixMilli() >= at
+}
+
+// shardIndex hashes the key with 32-bit FNV-1a, inlined to avoid allocating
+// a hash.Hash on every command.
+func (s *Storage) shardIndex(key string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+
+	return int(h % uint32(len(s.shards)))
+}
+
+// shard returns the shard the key belongs to.
+func (s *Storage) shard(key string) *shard {
+	return s.shards[s.shardIndex(key)]
+}
+
+// lockKey write-locks the shard of key, dropping the key if it has expired,
+// and returns the shard with the function that unlocks it.
+func (s *Storage) lockKey(key string) (*shard, func()) {
+	sh := s.shard(key)
+	if s.tx != nil {
+		s.tx.touch(sh, key)
+		return sh, func() {}
+	}
+
+	sh.lock(key)
+	return sh, sh.mu.Unlock
+}
+
+// rlockKey is lockKey for commands that only read.
+func (s *Storage) rlockKey(key string) (*shard, func()) {
+	sh := s.shard(key)
+	if s.tx != nil {
+		sh.expireIfNeeded(key)
+		return sh, func() {}
+	}
+
+	sh.rlock(key)
+	return sh, sh.mu.RUnlock
+}
+
+// lockKeys write-locks the shards of keys, dropping expired keys among them,
+// and returns the function that unlocks them. Shards are locked once each and
+// in index order, so that commands on several keys cannot deadlock.
+func (s *Storage) lockKeys(keys ...string) func() {
+	if s.tx != nil {
+		for _, key := range keys {
+			s.tx.touch(s.shard(key), key)
+		}
+		return func() {}
+	}
+
+	return s.lockShards(keys, (*shard).lock, func(sh *shard) { sh.mu.Unlock() })
+}
+
+// rlockKeys is lockKeys for commands that only read.
+func (s *Storage) rlockKeys(keys ...string) func() {
+	if s.tx != nil {
+		for _, key := range keys {
+			s.shard(key).expireIfNeeded(key)
+		}
+		return func() {}
+	}
+
+	return s.lockShards(keys, (*shard).rlock, func(sh *shard) { sh.mu.RUnlock() })
+}
+
+func (s *Storage) lockShards(keys []string, lock func(sh *shard, keys ...string), unlock func(sh *shard)) func() {
+	byShard := make(map[int][]string)
+	for _, key := range keys {
+		i := s.shardIndex(key)
+		byShard[i] = append(byShard[i], key)
+	}
+
+	order := make([]int, 0, len(byShard))
+	for i := range byShard {
+		order = append(order, i)
+	}
+	sort.Ints(order)
+
+	for _, i := range order {
+		lock(s.shards[i], byShard[i]...)
+	}
+
+	return func() {
+		for j := len(order) - 1; j >= 0; j-- {
+			unlock(s.shards[order[j]])
+		}
+	}
+}
+
+// rlockShard read-locks a single shard, for commands walking the keyspace
+// one shard at a time.
+func (s *Storage) rlockShard(sh *shard) func() {
+	if s.tx != nil {
+		return func() {}
+	}
+
+	sh.mu.RLock()
+	return sh.mu.RUnlock
+}
+
+// lockShard is rlockShard for writing.
+func (s *Storage) lockShard(sh *shard) func() {
+	if s.tx != nil {
+		return func() {}
+	}
+
+	sh.mu.Lock()
+	return sh.mu.Unlock
+}
+
+// lockAll write-locks every shard in order and returns the function that
+// unlocks them.
+func (s *Storage) lockAll() func() {
+	if s.tx != nil {
+		return func() {}
+	}
+
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+	}
+
+	return func() {
+		for i := len(s.shards) - 1; i >= 0; i-- {
+			s.shards[i].mu.Unlock()
+		}
+	}
+}
+
+// rlockAll is lockAll for reading.
+func (s *Storage) rlockAll() func() {
+	if s.tx != nil {
+		return func() {}
+	}
+
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+	}
+
+	return func() {
+		for i := len(s.shards) - 1; i >= 0; i-- {
+			s.shards[i].mu.RUnlock()
+		}
+	}
+}
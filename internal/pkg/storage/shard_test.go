@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestShardsSpreadKeys(t *testing.T) {
+	s, _ := NewStorage()
+
+	used := make(map[*shard]bool)
+	for i := 0; i < 1000; i++ {
+		used[s.shard(fmt.Sprintf("key:%d", i))] = true
+	}
+
+	if len(used) != defaultShardCount {
+		t.Errorf("1000 keys landed on %d shards, want %d", len(used), defaultShardCount)
+	}
+}
+
+func TestMultiKeyLockOrder(t *testing.T) {
+	s, _ := NewStorage()
+
+	// find two keys on different shards
+	a, b := "a", ""
+	for i := 0; b == ""; i++ {
+		if key := fmt.Sprint("b", i); s.shardIndex(key) != s.shardIndex(a) {
+			b = key
+		}
+	}
+	s.RPUSH(a, []any{1, 2, 3})
+	s.RPUSH(b, []any{4, 5, 6})
+	s.SADD("s:"+a, "x")
+	s.SADD("s:"+b, "y")
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				if i%2 == 0 {
+					s.LMove(a, b, "LEFT", "RIGHT")
+					s.SUNIONSTORE("s:"+a, "s:"+a, "s:"+b)
+				} else {
+					s.LMove(b, a, "LEFT", "RIGHT")
+					s.SUNIONSTORE("s:"+b, "s:"+b, "s:"+a)
+				}
+			}
+		}(i)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("commands on the same keys in opposite orders deadlocked")
+	}
+
+	la, _ := s.LLEN(a)
+	lb, _ := s.LLEN(b)
+	if la+lb != 6 {
+		t.Errorf("lists hold %d elements after the moves, want 6", la+lb)
+	}
+}
+
+func BenchmarkParallel(b *testing.B) {
+	var results strings.Builder
+
+	for _, shards := range []int{1, defaultShardCount} {
+		for _, workload := range []string{"Get", "Set", "Mixed"} {
+			name := fmt.Sprintf("%s/shards=%d", workload, shards)
+			b.Run(name, func(bb *testing.B) {
+				// logging would serialize the workers, measure the locks only
+				s, _ := NewStorage(WithShardCount(shards), WithLogger(zap.NewNop()))
+
+				keys := make([]string, 1024)
+				for i := range keys {
+					keys[i] = fmt.Sprintf("key:%d", i)
+					s.Set(keys[i], i)
+				}
+				bb.ResetTimer()
+
+				bb.RunParallel(func(pb *testing.PB) {
+					i := 0
+					for pb.Next() {
+						key := keys[i%len(keys)]
+						switch {
+						case workload == "Get", workload == "Mixed" && i%10 != 0:
+							_ = s.Get(key)
+						default:
+							s.Set(key, i)
+						}
+						i++
+					}
+				})
+
+				results.WriteString(fmt.Sprintf(
+					"Parallel%s: %d ops, %v/op\n",
+					name,
+					bb.N,
+					bb.Elapsed()/time.Duration(bb.N),
+				))
+			})
+		}
+	}
+	SaveBenchMarkResults(results.String())
+}
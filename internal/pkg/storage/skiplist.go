@@ -0,0 +1,265 @@
+package storage
+
+import "math/rand"
+
+const (
+	skiplistMaxLevel = 32
+	skiplistP        = 0.25
+)
+
+// skiplist keeps sorted set members ordered by score, then by member. Every
+// forward link records how many nodes it jumps over so that ranks can be
+// computed in O(log n).
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	level  int
+}
+
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode
+	level    []skiplistLevel
+}
+
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int
+}
+
+// scoreRange is a score interval, each end may be exclusive.
+type scoreRange struct {
+	min, max     float64
+	minEx, maxEx bool
+}
+
+// lexRange is a member interval for members sharing the same score.
+type lexRange struct {
+	min, max       string
+	minEx, maxEx   bool
+	minInf, maxInf bool
+}
+
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{level: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+
+	return level
+}
+
+// before reports whether the node sorts before (score, member).
+func (n *skiplistNode) before(score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// insert adds a member that must not already be in the list.
+func (sl *skiplist) insert(score float64, member string) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int
+
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		if i < sl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > sl.level {
+		for i := sl.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = sl.header
+			update[i].level[i].span = sl.length
+		}
+		sl.level = level
+	}
+
+	x = &skiplistNode{member: member, score: score, level: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+
+	for i := level; i < sl.level; i++ {
+		update[i].level[i].span++
+	}
+
+	if update[0] != sl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		sl.tail = x
+	}
+
+	sl.length++
+	return x
+}
+
+// delete removes the member with the given score, false if it is not there.
+func (sl *skiplist) delete(score float64, member string) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.before(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.level[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+
+	for i := 0; i < sl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		sl.tail = x.backward
+	}
+
+	for sl.level > 1 && sl.header.level[sl.level-1].forward == nil {
+		sl.level--
+	}
+
+	sl.length--
+	return true
+}
+
+// rank returns the 1-based rank of the member, 0 if it is not there.
+func (sl *skiplist) rank(score float64, member string) int {
+	rank := 0
+
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil &&
+			(x.level[i].forward.before(score, member) ||
+				(x.level[i].forward.score == score && x.level[i].forward.member == member)) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+
+		if x != sl.header && x.score == score && x.member == member {
+			return rank
+		}
+	}
+
+	return 0
+}
+
+// byRank returns the node at the 1-based rank, nil if out of range.
+func (sl *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+
+		if traversed == rank && x != sl.header {
+			return x
+		}
+	}
+
+	return nil
+}
+
+// firstInScoreRange returns the lowest node within r, nil if there is none.
+func (sl *skiplist) firstInScoreRange(r scoreRange) *skiplistNode {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.aboveMin(x.level[i].forward.score) {
+			x = x.level[i].forward
+		}
+	}
+
+	x = x.level[0].forward
+	if x == nil || !r.belowMax(x.score) {
+		return nil
+	}
+
+	return x
+}
+
+// firstInLexRange returns the lowest node within r, nil if there is none.
+func (sl *skiplist) firstInLexRange(r lexRange) *skiplistNode {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !r.aboveMin(x.level[i].forward.member) {
+			x = x.level[i].forward
+		}
+	}
+
+	x = x.level[0].forward
+	if x == nil || !r.belowMax(x.member) {
+		return nil
+	}
+
+	return x
+}
+
+func (r scoreRange) aboveMin(score float64) bool {
+	if r.minEx {
+		return score > r.min
+	}
+	return score >= r.min
+}
+
+func (r scoreRange) belowMax(score float64) bool {
+	if r.maxEx {
+		return score < r.max
+	}
+	return score <= r.max
+}
+
+func (r lexRange) aboveMin(member string) bool {
+	switch {
+	case r.minInf:
+		return true
+	case r.minEx:
+		return member > r.min
+	}
+	return member >= r.min
+}
+
+func (r lexRange) belowMax(member string) bool {
+	switch {
+	case r.maxInf:
+		return true
+	case r.maxEx:
+		return member < r.max
+	}
+	return member <= r.max
+}
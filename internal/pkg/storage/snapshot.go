@@ -0,0 +1,413 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+)
+
+// snapshotVersion is the version of the format SaveToFile writes. Files of
+// older versions are migrated when loaded.
+const snapshotVersion = 1
+
+// snapshot is the content of a file written by SaveToFile.
+type snapshot struct {
+	Version int           `json:"version"`
+	Keys    []snapshotKey `json:"keys"`
+}
+
+// snapshotKey holds a key of any type and its deadline in Unix milliseconds,
+// 0 if it has none. Only the field of its type is set.
+type snapshotKey struct {
+	Key      string           `json:"key"`
+	Type     KeyType          `json:"type"`
+	ExpireAt int64            `json:"expireAt,omitempty"`
+	Value    *Value           `json:"value,omitempty"`
+	List     []any            `json:"list,omitempty"`
+	Hash     map[string]Value `json:"hash,omitempty"`
+	Set      []string         `json:"set,omitempty"`
+	ZSet     []snapshotMember `json:"zset,omitempty"`
+}
+
+type snapshotMember struct {
+	Member string        `json:"member"`
+	Score  snapshotScore `json:"score"`
+}
+
+// snapshotScore writes infinite scores as "+inf" and "-inf", which JSON has
+// no number for.
+type snapshotScore float64
+
+func (sc snapshotScore) MarshalJSON() ([]byte, error) {
+	return json.Marshal(scoreArg(float64(sc)))
+}
+
+func (sc *snapshotScore) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		switch str {
+		case "+inf":
+			*sc = snapshotScore(math.Inf(1))
+		case "-inf":
+			*sc = snapshotScore(math.Inf(-1))
+		default:
+			return fmt.Errorf("invalid score %q", str)
+		}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*float64)(sc))
+}
+
+// snapshotEncoder writes a snapshot one key at a time.
+type snapshotEncoder interface {
+	writeKey(k *snapshotKey) error
+	// close writes what follows the last key.
+	close() error
+}
+
+// jsonEncoder writes the indented JSON document of a snapshot value.
+type jsonEncoder struct {
+	w    io.Writer
+	keys int
+}
+
+func newJSONEncoder(w io.Writer) (snapshotEncoder, error) {
+	if _, err := fmt.Fprintf(w, "{\n\t\"version\": %d,\n\t\"keys\": [", snapshotVersion); err != nil {
+		return nil, err
+	}
+	return &jsonEncoder{w: w}, nil
+}
+
+func (enc *jsonEncoder) writeKey(k *snapshotKey) error {
+	data, err := json.MarshalIndent(k, "\t\t", "\t")
+	if err != nil {
+		return fmt.Errorf("key %q: %w", k.Key, err)
+	}
+
+	sep := ",\n\t\t"
+	if enc.keys == 0 {
+		sep = "\n\t\t"
+	}
+	enc.keys++
+
+	if _, err := io.WriteString(enc.w, sep); err != nil {
+		return err
+	}
+	_, err = enc.w.Write(data)
+	return err
+}
+
+func (enc *jsonEncoder) close() error {
+	end := "]\n}\n"
+	if enc.keys > 0 {
+		end = "\n\t]\n}\n"
+	}
+
+	_, err := io.WriteString(enc.w, end)
+	return err
+}
+
+// legacySnapshot is the format of version 0, written before files had a
+// version. It held strings, lists and sorted sets without their deadlines.
+type legacySnapshot struct {
+	Inner map[string]Value     `json:"inner"`
+	List  map[string][]any     `json:"list"`
+	ZSet  map[string][]ZMember `json:"zset"`
+}
+
+// snapshotKey returns the key as it is, with its data copied so that later
+// changes do not reach the snapshot. It must be called with sh.mu held.
+func (sh *shard) snapshotKey(key string, typ KeyType) snapshotKey {
+	k := snapshotKey{Key: key, Type: typ, ExpireAt: sh.innerExpire[key]}
+
+	switch typ {
+	case TypeString:
+		// documents are replaced on change, never changed in place
+		val := sh.inner[key]
+		k.Value = &val
+	case TypeList:
+		k.List = slices.Clone(sh.list[key].Elem)
+	case TypeHash:
+		k.Hash = maps.Clone(sh.innerMap[key])
+	case TypeSet:
+		k.Set = sortedMembers(sh.sets[key])
+	case TypeZSet:
+		members := sh.zsets[key].members()
+		k.ZSet = make([]snapshotMember, len(members))
+		for i, m := range members {
+			k.ZSet[i] = snapshotMember{Member: m.Member, Score: snapshotScore(m.Score)}
+		}
+	}
+
+	return k
+}
+
+// expired reports whether the deadline of the key is at or before now, in
+// Unix milliseconds.
+func (k *snapshotKey) expired(now int64) bool {
+	return k.ExpireAt != 0 && k.ExpireAt <= now
+}
+
+// snapshotStage holds the keys of a snapshot being loaded until the load
+// applies them all at once. Each key is restored as soon as it is read, into
+// shards of the stage laid out as those of the storage.
+type snapshotStage struct {
+	shards  []*shard
+	index   func(key string) int
+	opts    LoadOptions
+	now     int64
+	skipped int
+}
+
+func (s *Storage) newSnapshotStage(opts LoadOptions) *snapshotStage {
+	st := &snapshotStage{
+		shards: make([]*shard, len(s.shards)),
+		index:  s.shardIndex,
+		opts:   opts,
+		now:    s.clock.Now().UnixMilli(),
+	}
+	for i := range st.shards {
+		st.shards[i] = newShard(s.logger, s.clock)
+	}
+
+	return st
+}
+
+// add stages a key checked by validate, unless the filter leaves it out or
+// its deadline has passed.
+func (st *snapshotStage) add(k snapshotKey) {
+	if !globMatch(st.opts.Match, k.Key) || k.expired(st.now) {
+		st.skipped++
+		return
+	}
+
+	sh := st.shards[st.index(k.Key)]
+	sh.removeKey(k.Key)
+	sh.restoreKey(k)
+}
+
+// applySnapshot loads the keys of st as its options say and reports the keys
+// it changed. On a dry run it only reports them. It must be called with every
+// shard locked.
+func (s *Storage) applySnapshot(st *snapshotStage) *LoadReport {
+	opts := st.opts
+	report := &LoadReport{DryRun: opts.DryRun, Skipped: st.skipped}
+
+	var load []string
+	for i, staged := range st.shards {
+		sh := s.shards[i]
+		staged.eachKey(func(key string, _ KeyType) {
+			switch {
+			case !sh.exists(key) || sh.isExpired(key):
+				report.Added = append(report.Added, key)
+			case opts.Mode == LoadMergeKeep:
+				report.Kept = append(report.Kept, key)
+				return
+			default:
+				report.Overwritten = append(report.Overwritten, key)
+			}
+			load = append(load, key)
+		})
+
+		if opts.Mode == LoadReplace {
+			sh.eachKey(func(key string, _ KeyType) {
+				if (!staged.exists(key) || staged.isExpired(key)) && globMatch(opts.Match, key) {
+					report.Removed = append(report.Removed, key)
+				}
+			})
+		}
+	}
+
+	sort.Strings(report.Added)
+	sort.Strings(report.Overwritten)
+	sort.Strings(report.Kept)
+	sort.Strings(report.Removed)
+	if opts.DryRun {
+		return report
+	}
+
+	changed := slices.Clone(report.Removed)
+	for _, key := range report.Removed {
+		sh := s.shard(key)
+		sh.preserve(key)
+		sh.removeKey(key)
+	}
+	for _, key := range load {
+		i := s.shardIndex(key)
+		sh := s.shards[i]
+		sh.preserve(key)
+		sh.removeKey(key)
+		sh.moveKey(st.shards[i], key)
+		sh.bumpVersion(key)
+		changed = append(changed, key)
+	}
+	s.propagateKeys(changed)
+
+	return report
+}
+
+// restoreKey must be called with sh.mu held, on a key checked by validate.
+func (sh *shard) restoreKey(k snapshotKey) {
+	switch k.Type {
+	case TypeString:
+		sh.inner[k.Key] = *k.Value
+	case TypeList:
+		sh.list[k.Key] = &List{Elem: k.List}
+	case TypeHash:
+		sh.innerMap[k.Key] = k.Hash
+	case TypeSet:
+		set := make(map[string]struct{}, len(k.Set))
+		for _, member := range k.Set {
+			set[member] = struct{}{}
+		}
+		sh.sets[k.Key] = set
+	case TypeZSet:
+		zs := newZSet()
+		for _, m := range k.ZSet {
+			zs.set(m.Member, float64(m.Score))
+		}
+		sh.zsets[k.Key] = zs
+	}
+
+	if k.ExpireAt != 0 {
+		sh.innerExpire[k.Key] = k.ExpireAt
+	}
+}
+
+// moveKey takes the key from another shard, which loses it. It must be called
+// with both shards locked and the key missing from sh.
+func (sh *shard) moveKey(from *shard, key string) {
+	switch from.keyType(key) {
+	case TypeString:
+		sh.inner[key] = from.inner[key]
+	case TypeList:
+		sh.list[key] = from.list[key]
+	case TypeHash:
+		sh.innerMap[key] = from.innerMap[key]
+	case TypeSet:
+		sh.sets[key] = from.sets[key]
+	case TypeZSet:
+		sh.zsets[key] = from.zsets[key]
+	}
+
+	if at, ok := from.innerExpire[key]; ok {
+		sh.innerExpire[key] = at
+	}
+	from.removeKey(key)
+}
+
+// validate checks that the key holds what its type needs. Containers cannot
+// be empty, an empty container is no key.
+func (k *snapshotKey) validate() error {
+	var ok bool
+	switch k.Type {
+	case TypeString:
+		ok = k.Value != nil
+	case TypeList:
+		ok = len(k.List) > 0
+	case TypeHash:
+		ok = len(k.Hash) > 0
+	case TypeSet:
+		ok = len(k.Set) > 0
+	case TypeZSet:
+		ok = len(k.ZSet) > 0
+	default:
+		return fmt.Errorf("key %q has unknown type %q", k.Key, k.Type)
+	}
+
+	if !ok {
+		return fmt.Errorf("key %q of type %s holds nothing", k.Key, k.Type)
+	}
+	return nil
+}
+
+// decodeSnapshot decodes a file written by SaveToFile, migrating it if it was
+// written in an older version of the format.
+func decodeSnapshot(data []byte) (*snapshot, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	var snap *snapshot
+	var err error
+	switch header.Version {
+	case 0:
+		snap, err = migrateLegacySnapshot(data)
+	case snapshotVersion:
+		snap = new(snapshot)
+		err = decodeNumbers(data, snap)
+	default:
+		return nil, fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range snap.Keys {
+		k := &snap.Keys[i]
+		if err := k.validate(); err != nil {
+			return nil, err
+		}
+		for j, elem := range k.List {
+			k.List[j] = restoreNumbers(elem)
+		}
+	}
+
+	return snap, nil
+}
+
+// migrateLegacySnapshot turns a version 0 file into a snapshot.
+func migrateLegacySnapshot(data []byte) (*snapshot, error) {
+	var legacy legacySnapshot
+	if err := decodeNumbers(data, &legacy); err != nil {
+		return nil, err
+	}
+
+	snap := &snapshot{Version: snapshotVersion}
+	for key, val := range legacy.Inner {
+		val := val
+		snap.Keys = append(snap.Keys, snapshotKey{Key: key, Type: TypeString, Value: &val})
+	}
+	for key, elems := range legacy.List {
+		if len(elems) > 0 {
+			snap.Keys = append(snap.Keys, snapshotKey{Key: key, Type: TypeList, List: elems})
+		}
+	}
+	for key, members := range legacy.ZSet {
+		if len(members) == 0 {
+			continue
+		}
+		k := snapshotKey{Key: key, Type: TypeZSet, ZSet: make([]snapshotMember, len(members))}
+		for i, m := range members {
+			k.ZSet[i] = snapshotMember{Member: m.Member, Score: snapshotScore(m.Score)}
+		}
+		snap.Keys = append(snap.Keys, k)
+	}
+
+	return snap, nil
+}
+
+// decodeNumbers decodes data into v leaving the numbers of untyped fields as
+// json.Number, for restoreNumbers to turn into int or float64.
+func decodeNumbers(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after snapshot")
+	}
+
+	return nil
+}
@@ -0,0 +1,533 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+)
+
+// The binary snapshot starts with binaryMagic and is a run of sections: a
+// header with the snapshot version, one section per key, and an end section
+// with the number of keys. A section is its type byte, the length of its
+// payload as a uvarint, the payload, and the big-endian CRC-32C of the type
+// and the payload. Files are written and read one section at a time.
+var binaryMagic = []byte("KVSNAP\x00")
+
+const (
+	sectionHeader byte = 'H'
+	sectionKey    byte = 'K'
+	sectionEnd    byte = 'E'
+
+	// maxSectionSize keeps a corrupt length from allocating the memory it claims.
+	maxSectionSize = 1 << 30
+)
+
+// Tags of the values in a binary snapshot.
+const (
+	tagNull byte = iota
+	tagFalse
+	tagTrue
+	tagInt
+	tagFloat
+	tagString
+	tagArray
+	tagObject
+)
+
+var (
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	// ErrSnapshotChecksum is returned when a section of a binary snapshot
+	// does not match its checksum.
+	ErrSnapshotChecksum = errors.New("snapshot section checksum mismatch")
+)
+
+var keyTypeCodes = map[KeyType]byte{
+	TypeString: 1,
+	TypeList:   2,
+	TypeHash:   3,
+	TypeSet:    4,
+	TypeZSet:   5,
+}
+
+// binaryEncoder writes a binary snapshot to w, a section as each key comes.
+type binaryEncoder struct {
+	w       io.Writer
+	keys    uint64
+	payload bytes.Buffer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func newBinaryEncoder(w io.Writer) (snapshotEncoder, error) {
+	enc := &binaryEncoder{w: w}
+	if _, err := w.Write(binaryMagic); err != nil {
+		return nil, err
+	}
+
+	enc.uvarint(snapshotVersion)
+	if err := enc.section(sectionHeader); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+func (enc *binaryEncoder) writeKey(k *snapshotKey) error {
+	if err := enc.key(k); err != nil {
+		enc.payload.Reset()
+		return fmt.Errorf("key %q: %w", k.Key, err)
+	}
+
+	enc.keys++
+	return enc.section(sectionKey)
+}
+
+func (enc *binaryEncoder) close() error {
+	enc.uvarint(enc.keys)
+	return enc.section(sectionEnd)
+}
+
+// section writes the payload built so far as a section of type typ.
+func (enc *binaryEncoder) section(typ byte) error {
+	payload := enc.payload.Bytes()
+	defer enc.payload.Reset()
+
+	crc := crc32.Update(0, crcTable, []byte{typ})
+	crc = crc32.Update(crc, crcTable, payload)
+
+	head := append([]byte{typ}, enc.scratch[:binary.PutUvarint(enc.scratch[:], uint64(len(payload)))]...)
+	if _, err := enc.w.Write(head); err != nil {
+		return err
+	}
+	if _, err := enc.w.Write(payload); err != nil {
+		return err
+	}
+
+	return binary.Write(enc.w, binary.BigEndian, crc)
+}
+
+func (enc *binaryEncoder) key(k *snapshotKey) error {
+	enc.string(k.Key)
+	enc.payload.WriteByte(keyTypeCodes[k.Type])
+	enc.varint(k.ExpireAt)
+
+	switch k.Type {
+	case TypeString:
+		return enc.value(k.Value.Val)
+	case TypeList:
+		enc.uvarint(uint64(len(k.List)))
+		for _, elem := range k.List {
+			if err := enc.value(elem); err != nil {
+				return err
+			}
+		}
+	case TypeHash:
+		fields := make([]string, 0, len(k.Hash))
+		for field := range k.Hash {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		enc.uvarint(uint64(len(fields)))
+		for _, field := range fields {
+			enc.string(field)
+			if err := enc.value(k.Hash[field].Val); err != nil {
+				return err
+			}
+		}
+	case TypeSet:
+		enc.uvarint(uint64(len(k.Set)))
+		for _, member := range k.Set {
+			enc.string(member)
+		}
+	case TypeZSet:
+		enc.uvarint(uint64(len(k.ZSet)))
+		for _, m := range k.ZSet {
+			enc.string(m.Member)
+			enc.float(float64(m.Score))
+		}
+	}
+
+	return nil
+}
+
+func (enc *binaryEncoder) value(val any) error {
+	switch v := val.(type) {
+	case nil:
+		enc.payload.WriteByte(tagNull)
+	case bool:
+		if v {
+			enc.payload.WriteByte(tagTrue)
+		} else {
+			enc.payload.WriteByte(tagFalse)
+		}
+	case int:
+		enc.payload.WriteByte(tagInt)
+		enc.varint(int64(v))
+	case int64:
+		enc.payload.WriteByte(tagInt)
+		enc.varint(v)
+	case float64:
+		enc.payload.WriteByte(tagFloat)
+		enc.float(v)
+	case string:
+		enc.payload.WriteByte(tagString)
+		enc.string(v)
+	case []any:
+		enc.payload.WriteByte(tagArray)
+		enc.uvarint(uint64(len(v)))
+		for _, elem := range v {
+			if err := enc.value(elem); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		enc.payload.WriteByte(tagObject)
+		enc.uvarint(uint64(len(names)))
+		for _, name := range names {
+			enc.string(name)
+			if err := enc.value(v[name]); err != nil {
+				return err
+			}
+		}
+	default:
+		// list elements are not checked on push, they are saved as the
+		// JSON format would save them
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		decoded, err := decodeJSONValue(data)
+		if err != nil {
+			return err
+		}
+		return enc.value(decoded)
+	}
+
+	return nil
+}
+
+func (enc *binaryEncoder) uvarint(n uint64) {
+	enc.payload.Write(enc.scratch[:binary.PutUvarint(enc.scratch[:], n)])
+}
+
+func (enc *binaryEncoder) varint(n int64) {
+	enc.payload.Write(enc.scratch[:binary.PutVarint(enc.scratch[:], n)])
+}
+
+func (enc *binaryEncoder) float(f float64) {
+	enc.payload.Write(binary.LittleEndian.AppendUint64(enc.scratch[:0], math.Float64bits(f)))
+}
+
+func (enc *binaryEncoder) string(s string) {
+	enc.uvarint(uint64(len(s)))
+	enc.payload.WriteString(s)
+}
+
+// isBinarySnapshot reports whether r starts with binaryMagic, without
+// consuming anything.
+func isBinarySnapshot(r *bufio.Reader) bool {
+	head, _ := r.Peek(len(binaryMagic))
+	return bytes.Equal(head, binaryMagic)
+}
+
+// decodeBinarySnapshot reads a binary snapshot written by binaryEncoder and
+// calls add with each key once its section is read and checked.
+func decodeBinarySnapshot(r *bufio.Reader, add func(k snapshotKey)) error {
+	if _, err := r.Discard(len(binaryMagic)); err != nil {
+		return err
+	}
+
+	typ, payload, err := readSection(r)
+	if err != nil {
+		return err
+	}
+	if typ != sectionHeader {
+		return fmt.Errorf("snapshot starts with section %q", typ)
+	}
+	dec := &binaryDecoder{buf: payload}
+	version := dec.uvarint()
+	if dec.err != nil {
+		return dec.err
+	}
+	if version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", version)
+	}
+
+	var keys uint64
+	for {
+		typ, payload, err := readSection(r)
+		if err != nil {
+			return err
+		}
+		dec := &binaryDecoder{buf: payload}
+
+		switch typ {
+		case sectionKey:
+			k := dec.key()
+			if dec.err == nil && len(dec.buf) > 0 {
+				dec.err = errors.New("unexpected data after key")
+			}
+			if dec.err != nil {
+				return fmt.Errorf("key %d: %w", keys, dec.err)
+			}
+			if err := k.validate(); err != nil {
+				return err
+			}
+			add(k)
+			keys++
+		case sectionEnd:
+			if n := dec.uvarint(); dec.err != nil || n != keys {
+				return fmt.Errorf("snapshot ends after %d keys, it claims %d", keys, n)
+			}
+			return nil
+		default:
+			return fmt.Errorf("unknown snapshot section %q", typ)
+		}
+	}
+}
+
+// readSection reads a section and checks it against its checksum. A file
+// cut short is io.ErrUnexpectedEOF.
+func readSection(r *bufio.Reader) (byte, []byte, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, noEOF(err)
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, noEOF(err)
+	}
+	if size > maxSectionSize {
+		return 0, nil, fmt.Errorf("snapshot section of %d bytes", size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, noEOF(err)
+	}
+	var crc uint32
+	if err := binary.Read(r, binary.BigEndian, &crc); err != nil {
+		return 0, nil, noEOF(err)
+	}
+
+	want := crc32.Update(0, crcTable, []byte{typ})
+	if crc32.Update(want, crcTable, payload) != crc {
+		return 0, nil, ErrSnapshotChecksum
+	}
+
+	return typ, payload, nil
+}
+
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// binaryDecoder reads the payload of a section. The first error sticks, the
+// reads after it return zero values.
+type binaryDecoder struct {
+	buf []byte
+	err error
+}
+
+var errShortSection = errors.New("snapshot section too short")
+
+func (dec *binaryDecoder) key() snapshotKey {
+	k := snapshotKey{Key: dec.string()}
+
+	code := dec.byte()
+	for typ, c := range keyTypeCodes {
+		if c == code {
+			k.Type = typ
+		}
+	}
+	if k.Type == "" && dec.err == nil {
+		dec.err = fmt.Errorf("unknown key type %d", code)
+	}
+	k.ExpireAt = dec.varint()
+
+	switch k.Type {
+	case TypeString:
+		if val, ok := dec.storedValue(); ok {
+			k.Value = &val
+		}
+	case TypeList:
+		n := dec.count(1)
+		k.List = make([]any, 0, n)
+		for i := 0; i < n && dec.err == nil; i++ {
+			k.List = append(k.List, dec.value())
+		}
+	case TypeHash:
+		n := dec.count(2)
+		k.Hash = make(map[string]Value, n)
+		for i := 0; i < n && dec.err == nil; i++ {
+			field := dec.string()
+			if val, ok := dec.storedValue(); ok {
+				k.Hash[field] = val
+			}
+		}
+	case TypeSet:
+		n := dec.count(1)
+		k.Set = make([]string, 0, n)
+		for i := 0; i < n && dec.err == nil; i++ {
+			k.Set = append(k.Set, dec.string())
+		}
+	case TypeZSet:
+		n := dec.count(9)
+		k.ZSet = make([]snapshotMember, 0, n)
+		for i := 0; i < n && dec.err == nil; i++ {
+			k.ZSet = append(k.ZSet, snapshotMember{Member: dec.string(), Score: snapshotScore(dec.float())})
+		}
+	}
+
+	return k
+}
+
+// storedValue reads a value and gives it its kind, as Set would.
+func (dec *binaryDecoder) storedValue() (Value, bool) {
+	raw := dec.value()
+	if dec.err != nil {
+		return Value{}, false
+	}
+
+	val, err := newValue(raw)
+	if err != nil {
+		dec.err = err
+		return Value{}, false
+	}
+	return val, true
+}
+
+func (dec *binaryDecoder) value() any {
+	switch tag := dec.byte(); tag {
+	case tagNull:
+		return nil
+	case tagFalse:
+		return false
+	case tagTrue:
+		return true
+	case tagInt:
+		n := dec.varint()
+		if int64(int(n)) != n {
+			return float64(n)
+		}
+		return int(n)
+	case tagFloat:
+		return dec.float()
+	case tagString:
+		return dec.string()
+	case tagArray:
+		n := dec.count(1)
+		arr := make([]any, 0, n)
+		for i := 0; i < n && dec.err == nil; i++ {
+			arr = append(arr, dec.value())
+		}
+		return arr
+	case tagObject:
+		n := dec.count(2)
+		obj := make(map[string]any, n)
+		for i := 0; i < n && dec.err == nil; i++ {
+			name := dec.string()
+			obj[name] = dec.value()
+		}
+		return obj
+	default:
+		if dec.err == nil {
+			dec.err = fmt.Errorf("unknown value tag %d", tag)
+		}
+		return nil
+	}
+}
+
+// count reads the length of a collection whose elements take at least min
+// bytes each, and fails if the payload is too short to hold them.
+func (dec *binaryDecoder) count(min int) int {
+	n := dec.uvarint()
+	if n > uint64(len(dec.buf)/min) {
+		dec.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (dec *binaryDecoder) byte() byte {
+	if dec.err != nil || len(dec.buf) < 1 {
+		dec.fail()
+		return 0
+	}
+
+	b := dec.buf[0]
+	dec.buf = dec.buf[1:]
+	return b
+}
+
+func (dec *binaryDecoder) uvarint() uint64 {
+	if dec.err != nil {
+		return 0
+	}
+
+	n, size := binary.Uvarint(dec.buf)
+	if size <= 0 {
+		dec.fail()
+		return 0
+	}
+	dec.buf = dec.buf[size:]
+	return n
+}
+
+func (dec *binaryDecoder) varint() int64 {
+	if dec.err != nil {
+		return 0
+	}
+
+	n, size := binary.Varint(dec.buf)
+	if size <= 0 {
+		dec.fail()
+		return 0
+	}
+	dec.buf = dec.buf[size:]
+	return n
+}
+
+func (dec *binaryDecoder) float() float64 {
+	if dec.err != nil || len(dec.buf) < 8 {
+		dec.fail()
+		return 0
+	}
+
+	f := math.Float64frombits(binary.LittleEndian.Uint64(dec.buf))
+	dec.buf = dec.buf[8:]
+	return f
+}
+
+func (dec *binaryDecoder) string() string {
+	n := dec.count(1)
+	if dec.err != nil {
+		return ""
+	}
+
+	s := string(dec.buf[:n])
+	dec.buf = dec.buf[n:]
+	return s
+}
+
+func (dec *binaryDecoder) fail() {
+	if dec.err == nil {
+		dec.err = errShortSection
+	}
+}
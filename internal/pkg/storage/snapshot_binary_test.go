@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestBinarySnapshotRoundTrip(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	kinds := []any{1 << 60, 2.5, 2.0, "str", true, false, nil, map[string]any{"n": -3, "list": []any{1.5, "x", map[string]any{}}}}
+
+	s.Set("string", "value")
+	s.SetWithTTL("doc", map[string]any{"a": []any{1, 2.5}}, time.Hour)
+	s.RPUSH("list", kinds)
+	s.Expire("list", time.Hour)
+	for i, kind := range kinds {
+		s.HSET("hash", string(rune('a'+i)), kind)
+	}
+	s.SADD("set", "x", "", "z")
+	s.ZADD("zset", ZMember{"ann", 1.5}, ZMember{"top", math.Inf(1)}, ZMember{"bottom", math.Inf(-1)})
+	s.Set("", "empty key")
+
+	path := filepath.Join(t.TempDir(), "dump.snap")
+	if err := s.SaveSnapshot(path, FormatBinary); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+
+	loaded, _ := NewStorage()
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+
+	if got, want := dump(t, &loaded), dump(t, &s); got != want {
+		t.Errorf("loaded storage differs\n got: %s\nwant: %s", got, want)
+	}
+	// unlike JSON, the binary format keeps whole floats as float64
+	if list, _ := loaded.LRANGE("list", 0, -1); !reflect.DeepEqual(list, kinds) {
+		t.Errorf("list: got %#v", list)
+	}
+	if ttl := loaded.TTL("doc"); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("TTL doc: got %v", ttl)
+	}
+}
+
+func TestBinarySnapshotDetectsDamage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.snap")
+
+	s, _ := NewStorage()
+	s.Set("a", "needle")
+	s.RPUSH("b", []any{1, 2, 3})
+	if err := s.SaveSnapshot(path, FormatBinary); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+	saved, _ := os.ReadFile(path)
+
+	flipped := bytes.Clone(saved)
+	flipped[bytes.Index(flipped, []byte("needle"))] ^= 1
+	truncated := saved[:len(saved)-3]
+
+	for name, tc := range map[string]struct {
+		data []byte
+		err  error
+	}{
+		"flipped":   {flipped, ErrSnapshotChecksum},
+		"truncated": {truncated, io.ErrUnexpectedEOF},
+	} {
+		os.WriteFile(path, tc.data, 0644)
+
+		loaded, _ := NewStorage()
+		loaded.Set("kept", 1)
+		if err := loaded.LoadFromFile(path); !errors.Is(err, tc.err) {
+			t.Errorf("%s: got %v, want %v", name, err, tc.err)
+		}
+		if loaded.Exists("kept") != 1 || loaded.Exists("a") != 0 {
+			t.Errorf("%s: failed load changed the storage", name)
+		}
+	}
+}
+
+func TestBinarySnapshotStreamsKeys(t *testing.T) {
+	s, _ := NewStorage()
+	for i := 0; i < 10; i++ {
+		s.Set(fmt.Sprintf("key:%d", i), i)
+	}
+
+	var buf bytes.Buffer
+	enc, _ := newBinaryEncoder(&buf)
+	s.beginView()
+	s.readView(enc.writeKey)
+	enc.close()
+	saved := buf.Bytes()
+
+	// keys are handed over as their sections are read, before the end of
+	// the file is checked
+	var keys []string
+	err := decodeBinarySnapshot(bufio.NewReader(bytes.NewReader(saved[:len(saved)-3])), func(k snapshotKey) {
+		keys = append(keys, k.Key)
+	})
+	if !errors.Is(err, io.ErrUnexpectedEOF) || len(keys) != 10 {
+		t.Errorf("truncated snapshot: got %d keys, %v", len(keys), err)
+	}
+}
+
+func TestSnapshotSchedulerBinaryFormat(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := NewStorage()
+
+	if err := s.StartSnapshots(SnapshotConfig{Dir: dir, Format: "xml", Interval: time.Second}); err == nil {
+		t.Errorf("unknown format accepted")
+	}
+
+	s.Set("a", 1)
+	if err := s.StartSnapshots(SnapshotConfig{Dir: dir, Format: FormatBinary, Interval: time.Hour}); err != nil {
+		t.Fatalf("StartSnapshots: %v", err)
+	}
+	s.StopSnapshots(context.Background())
+
+	files, _ := listSnapshots(dir, defaultSnapshotPrefix)
+	if len(files) != 1 || !strings.HasSuffix(files[0], ".snap") {
+		t.Fatalf("files: got %v", files)
+	}
+	loaded, _ := NewStorage()
+	if err := loaded.LoadFromFile(files[0]); err != nil || loaded.Exists("a") != 1 {
+		t.Errorf("LoadFromFile: %v", err)
+	}
+}
+
+func BenchmarkSnapshot(b *testing.B) {
+	s, _ := NewStorage(WithLogger(zap.NewNop()))
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		switch i % 5 {
+		case 0:
+			s.Set(key, map[string]any{"id": i, "name": key, "score": float64(i) / 3})
+		case 1:
+			s.RPUSH(key, []any{i, "elem", 1.5, true})
+		case 2:
+			s.HSET(key, "field", i)
+			s.HSET(key, "other", key)
+		case 3:
+			s.SADD(key, "a", "b", key)
+		case 4:
+			s.ZADD(key, ZMember{"a", 1}, ZMember{key, float64(i)})
+		}
+	}
+
+	var results strings.Builder
+
+	for _, format := range []SnapshotFormat{FormatJSON, FormatBinary} {
+		path := filepath.Join(b.TempDir(), "dump")
+
+		b.Run("Save/"+string(format), func(bb *testing.B) {
+			bb.ReportAllocs()
+			for i := 0; i < bb.N; i++ {
+				if err := s.SaveSnapshot(path, format); err != nil {
+					bb.Fatal(err)
+				}
+			}
+			stat, _ := os.Stat(path)
+			bb.ReportMetric(float64(stat.Size()), "bytes/file")
+
+			results.WriteString(fmt.Sprintf(
+				"SnapshotSave/%s: %d ops, %v/op, %d bytes\n",
+				format,
+				bb.N,
+				bb.Elapsed()/time.Duration(bb.N),
+				stat.Size(),
+			))
+		})
+
+		b.Run("Load/"+string(format), func(bb *testing.B) {
+			loaded, _ := NewStorage(WithLogger(zap.NewNop()))
+			bb.ReportAllocs()
+			bb.ResetTimer()
+
+			for i := 0; i < bb.N; i++ {
+				if err := loaded.LoadFromFile(path); err != nil {
+					bb.Fatal(err)
+				}
+			}
+
+			results.WriteString(fmt.Sprintf(
+				"SnapshotLoad/%s: %d ops, %v/op\n",
+				format,
+				bb.N,
+				bb.Elapsed()/time.Duration(bb.N),
+			))
+		})
+	}
+	SaveBenchMarkResults(results.String())
+}
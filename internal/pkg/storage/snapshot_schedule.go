@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// snapshotCheckInterval is how often the scheduler checks its rules.
+	snapshotCheckInterval = 100 * time.Millisecond
+	// snapshotTimeFormat names the files, it sorts in the order they were saved.
+	snapshotTimeFormat    = "20060102T150405.000Z"
+	defaultSnapshotPrefix = "dump"
+)
+
+// snapshotExtensions ends the names of the files of each format.
+var snapshotExtensions = map[SnapshotFormat]string{
+	FormatJSON:   ".json",
+	FormatBinary: ".snap",
+}
+
+// ErrSnapshotsRunning is returned by StartSnapshots when the scheduler already runs.
+var ErrSnapshotsRunning = errors.New("snapshot scheduler already running")
+
+// SaveRule saves a snapshot once Changes changes were made and Within has
+// passed since the last one.
+type SaveRule struct {
+	Changes int64
+	Within  time.Duration
+}
+
+// SnapshotConfig tells the scheduler when and where to save snapshots.
+type SnapshotConfig struct {
+	// Dir holds the files, named Prefix-<UTC time>.json, or .snap in the
+	// binary format. Prefix is "dump" if empty.
+	Dir    string
+	Prefix string
+	// Format is the encoding of the files, FormatJSON if empty.
+	Format SnapshotFormat
+	// Rules save a snapshot as soon as one of them is met.
+	Rules []SaveRule
+	// Interval saves a snapshot that often if anything changed, 0 for never.
+	Interval time.Duration
+	// Retain is how many files are kept, the oldest are removed. 0 keeps all.
+	Retain int
+}
+
+// SnapshotStatus describes the snapshot scheduler and its last save.
+type SnapshotStatus struct {
+	Running          bool          `json:"running"`
+	Changes          int64         `json:"changes"`
+	Saves            int           `json:"saves"`
+	LastSave         time.Time     `json:"lastSave"`
+	LastSaveFile     string        `json:"lastSaveFile,omitempty"`
+	LastSaveDuration time.Duration `json:"lastSaveDuration"`
+	LastSaveError    string        `json:"lastSaveError,omitempty"`
+}
+
+// snapshotScheduler saves snapshots in the background.
+type snapshotScheduler struct {
+	mu     sync.Mutex
+	cfg    SnapshotConfig
+	stop   chan struct{}
+	done   chan struct{}
+	status SnapshotStatus
+}
+
+// StartSnapshots starts saving snapshots to cfg.Dir in the background, as
+// the rules and the interval of cfg say. The time since the last save counts
+// from now.
+func (s *Storage) StartSnapshots(cfg SnapshotConfig) error {
+	if len(cfg.Rules) == 0 && cfg.Interval <= 0 {
+		return errors.New("no save rule nor interval")
+	}
+	for _, rule := range cfg.Rules {
+		if rule.Changes <= 0 || rule.Within < 0 {
+			return fmt.Errorf("invalid save rule %+v", rule)
+		}
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultSnapshotPrefix
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatJSON
+	}
+	if _, ok := snapshotExtensions[cfg.Format]; !ok {
+		return fmt.Errorf("unknown snapshot format %q", cfg.Format)
+	}
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	sc := s.snapshots
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.stop != nil {
+		return ErrSnapshotsRunning
+	}
+	sc.cfg = cfg
+	sc.stop = make(chan struct{})
+	sc.done = make(chan struct{})
+	sc.status.Running = true
+	go s.scheduleSnapshots(cfg, sc.stop, sc.done)
+
+	s.logger.Info("snapshot scheduler started",
+		zap.String("dir", cfg.Dir),
+		zap.Int("rules", len(cfg.Rules)),
+		zap.Duration("interval", cfg.Interval))
+	return nil
+}
+
+// StopSnapshots stops the scheduler, saving a last snapshot if anything
+// changed since the previous one. It waits for that save until ctx is done.
+func (s *Storage) StopSnapshots(ctx context.Context) error {
+	sc := s.snapshots
+	sc.mu.Lock()
+	stop, done := sc.stop, sc.done
+	sc.stop = nil
+	sc.mu.Unlock()
+
+	if stop == nil {
+		return nil
+	}
+	close(stop)
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SnapshotStatus returns the state of the scheduler.
+func (s *Storage) SnapshotStatus() SnapshotStatus {
+	sc := s.snapshots
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	status := sc.status
+	status.Changes = s.dirty.Load()
+	return status
+}
+
+func (s *Storage) scheduleSnapshots(cfg SnapshotConfig, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(snapshotCheckInterval)
+	defer ticker.Stop()
+
+	lastSave := s.clock.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			if s.dirty.Load() > 0 {
+				s.saveSnapshot(cfg)
+			}
+			s.snapshots.mu.Lock()
+			// unless a scheduler started since has taken over
+			if s.snapshots.done == done {
+				s.snapshots.status.Running = false
+			}
+			s.snapshots.mu.Unlock()
+
+			s.logger.Info("snapshot scheduler stopped")
+			return
+		}
+
+		now := s.clock.Now()
+		if !cfg.due(s.dirty.Load(), now.Sub(lastSave)) {
+			continue
+		}
+		// the wait counts from when the save began, and a failed save is
+		// retried after the same wait as a successful one
+		s.saveSnapshot(cfg)
+		lastSave = now
+	}
+}
+
+// due reports whether a snapshot is to be saved after changes changes in elapsed.
+func (cfg *SnapshotConfig) due(changes int64, elapsed time.Duration) bool {
+	if changes == 0 {
+		return false
+	}
+	if cfg.Interval > 0 && elapsed >= cfg.Interval {
+		return true
+	}
+	for _, rule := range cfg.Rules {
+		if changes >= rule.Changes && elapsed >= rule.Within {
+			return true
+		}
+	}
+
+	return false
+}
+
+// saveSnapshot saves a new file, removes the files past the retention count
+// and records how it went.
+func (s *Storage) saveSnapshot(cfg SnapshotConfig) {
+	began := s.clock.Now()
+	name := cfg.Prefix + "-" + began.UTC().Format(snapshotTimeFormat) + snapshotExtensions[cfg.Format]
+	path := filepath.Join(cfg.Dir, name)
+
+	err := s.SaveSnapshot(path, cfg.Format)
+	took := s.clock.Now().Sub(began)
+	if err == nil {
+		s.removeOldSnapshots(cfg)
+	}
+
+	sc := s.snapshots
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.status.LastSaveDuration = took
+	if err != nil {
+		sc.status.LastSaveError = err.Error()
+		s.logger.Error("snapshot not saved", zap.String("file", path), zap.Error(err))
+		return
+	}
+	sc.status.Saves++
+	sc.status.LastSave = began
+	sc.status.LastSaveFile = path
+	sc.status.LastSaveError = ""
+}
+
+// removeOldSnapshots keeps the cfg.Retain newest files of the scheduler.
+func (s *Storage) removeOldSnapshots(cfg SnapshotConfig) {
+	if cfg.Retain <= 0 {
+		return
+	}
+
+	files, err := listSnapshots(cfg.Dir, cfg.Prefix)
+	if err != nil {
+		s.logger.Warn("old snapshots not removed", zap.Error(err))
+		return
+	}
+
+	for _, file := range files[:max(len(files)-cfg.Retain, 0)] {
+		if err := os.Remove(file); err != nil {
+			s.logger.Warn("old snapshot not removed", zap.String("file", file), zap.Error(err))
+		}
+	}
+}
+
+// listSnapshots returns the files saved by a scheduler with prefix in dir,
+// in any format, oldest first.
+func listSnapshots(dir, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		stamp, ok := strings.CutPrefix(name, prefix+"-")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		for _, ext := range snapshotExtensions {
+			at, ok := strings.CutSuffix(stamp, ext)
+			if _, err := time.Parse(snapshotTimeFormat, at); ok && err == nil {
+				files = append(files, filepath.Join(dir, name))
+			}
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func waitSaves(t *testing.T, s *Storage, saves int) SnapshotStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status := s.SnapshotStatus()
+		if status.Saves >= saves {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d snapshots saved, want %d", status.Saves, saves)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSnapshotRules(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	err = s.StartSnapshots(SnapshotConfig{Dir: dir, Rules: []SaveRule{{Changes: 3, Within: 0}}})
+	if err != nil {
+		t.Fatalf("StartSnapshots: %v", err)
+	}
+	defer s.StopSnapshots(context.Background())
+
+	s.Set("a", 1)
+	s.Set("b", 2)
+	time.Sleep(3 * snapshotCheckInterval)
+	if status := s.SnapshotStatus(); status.Saves != 0 || status.Changes != 2 {
+		t.Errorf("status before the rule is met: got %+v", status)
+	}
+
+	s.Set("c", 3)
+	status := waitSaves(t, &s, 1)
+	if status.Changes != 0 || status.LastSaveError != "" || !status.Running {
+		t.Errorf("status after save: got %+v", status)
+	}
+
+	loaded, _ := NewStorage()
+	if err := loaded.LoadFromFile(status.LastSaveFile); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	if n := loaded.Exists("a", "b", "c"); n != 3 {
+		t.Errorf("snapshot holds %d keys", n)
+	}
+}
+
+func TestSnapshotIntervalAndRetention(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := NewStorage()
+
+	cfg := SnapshotConfig{Dir: dir, Prefix: "kv", Interval: snapshotCheckInterval, Retain: 2}
+	if err := s.StartSnapshots(cfg); err != nil {
+		t.Fatalf("StartSnapshots: %v", err)
+	}
+	defer s.StopSnapshots(context.Background())
+
+	for i := 1; i <= 4; i++ {
+		s.Set("n", i)
+		waitSaves(t, &s, i)
+	}
+
+	// nothing changed, nothing is saved
+	time.Sleep(3 * snapshotCheckInterval)
+	if status := s.SnapshotStatus(); status.Saves != 4 {
+		t.Errorf("Saves without changes: got %d", status.Saves)
+	}
+
+	files, _ := listSnapshots(dir, "kv")
+	if len(files) != 2 {
+		t.Fatalf("files kept: got %v", files)
+	}
+
+	loaded, _ := NewStorage()
+	if err := loaded.LoadFromFile(files[1]); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	if got := loaded.Get("n"); got == nil || *got != 4 {
+		t.Errorf("newest snapshot: got %v", got)
+	}
+}
+
+func TestSnapshotRulesFollowClock(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	s, err := NewStorage(WithClock(clock))
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	cfg := SnapshotConfig{Dir: dir, Prefix: "kv", Rules: []SaveRule{{Changes: 1, Within: time.Hour}}, Retain: 2}
+	if err := s.StartSnapshots(cfg); err != nil {
+		t.Fatalf("StartSnapshots: %v", err)
+	}
+	defer s.StopSnapshots(context.Background())
+
+	s.Set("n", 0)
+	clock.advance(59 * time.Minute)
+	time.Sleep(3 * snapshotCheckInterval)
+	if status := s.SnapshotStatus(); status.Saves != 0 {
+		t.Errorf("Saves before the hour: got %d", status.Saves)
+	}
+
+	var names []string
+	for i := 1; i <= 3; i++ {
+		clock.advance(time.Hour)
+		s.Set("n", i)
+		status := waitSaves(t, &s, i)
+		if !status.LastSave.Equal(clock.Now()) || status.LastSaveDuration != 0 {
+			t.Errorf("save %d: got %+v", i, status)
+		}
+		names = append(names, filepath.Join(dir, "kv-"+clock.Now().Format(snapshotTimeFormat)+".json"))
+	}
+
+	files, _ := listSnapshots(dir, "kv")
+	if !reflect.DeepEqual(files, names[1:]) {
+		t.Errorf("files kept: got %v, want %v", files, names[1:])
+	}
+}
+
+func TestStopSnapshotsSavesChanges(t *testing.T) {
+	dir := t.TempDir()
+	s, _ := NewStorage()
+
+	cfg := SnapshotConfig{Dir: dir, Rules: []SaveRule{{Changes: 1000, Within: time.Hour}}}
+	if err := s.StartSnapshots(cfg); err != nil {
+		t.Fatalf("StartSnapshots: %v", err)
+	}
+	if err := s.StartSnapshots(cfg); !errors.Is(err, ErrSnapshotsRunning) {
+		t.Errorf("second StartSnapshots: got %v", err)
+	}
+
+	s.SADD("set", "x")
+	if err := s.StopSnapshots(context.Background()); err != nil {
+		t.Fatalf("StopSnapshots: %v", err)
+	}
+
+	status := s.SnapshotStatus()
+	if status.Running || status.Saves != 1 || status.Changes != 0 {
+		t.Errorf("status after stop: got %+v", status)
+	}
+	if files, _ := listSnapshots(dir, defaultSnapshotPrefix); len(files) != 1 {
+		t.Errorf("files: got %v", files)
+	}
+	if err := s.StopSnapshots(context.Background()); err != nil {
+		t.Errorf("StopSnapshots when stopped: %v", err)
+	}
+}
+
+func TestStartSnapshotsRejectsBadConfig(t *testing.T) {
+	s, _ := NewStorage()
+
+	for _, cfg := range []SnapshotConfig{
+		{Dir: t.TempDir()},
+		{Dir: t.TempDir(), Rules: []SaveRule{{Changes: 0, Within: time.Second}}},
+	} {
+		if err := s.StartSnapshots(cfg); err == nil {
+			t.Errorf("StartSnapshots(%+v) succeeded", cfg)
+			s.StopSnapshots(context.Background())
+		}
+	}
+}
+
+func TestSaveNeverLeavesNegativeChanges(t *testing.T) {
+	s, _ := NewStorage()
+	s.Set("a", 1)
+	s.Set("b", 2)
+
+	// the count dropped since the save began, as on a load
+	s.dirty.Store(1)
+	s.clearDirty(2)
+	if n := s.SnapshotStatus().Changes; n != 0 {
+		t.Errorf("changes after a save: got %d", n)
+	}
+
+	s.Set("c", 3)
+	s.clearDirty(0)
+	if n := s.SnapshotStatus().Changes; n != 1 {
+		t.Errorf("changes after an empty save: got %d", n)
+	}
+}
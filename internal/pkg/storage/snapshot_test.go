@@ -0,0 +1,330 @@
+package storage
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	kinds := []any{1 << 60, 2.5, "str", true, nil, map[string]any{"n": 3, "list": []any{1.5, "x"}}}
+
+	s.Set("string", "value")
+	s.SetWithTTL("doc", map[string]any{"a": []any{1, 2.5}}, time.Hour)
+	s.RPUSH("list", kinds)
+	s.Expire("list", time.Hour)
+	for i, kind := range kinds {
+		s.HSET("hash", string(rune('a'+i)), kind)
+	}
+	s.SADD("set", "x", "y", "z")
+	s.ZADD("zset", ZMember{"ann", 1.5}, ZMember{"top", math.Inf(1)}, ZMember{"bottom", math.Inf(-1)})
+	s.Expire("zset", 2*time.Hour)
+
+	path := filepath.Join(t.TempDir(), "dump.json")
+	if err := s.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile: %v", err)
+	}
+
+	loaded, _ := NewStorage()
+	loaded.Set("stale", 1)
+	loaded.HSET("hash", "stale", 1)
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+
+	if got, want := dump(t, &loaded), dump(t, &s); got != want {
+		t.Errorf("loaded storage differs\n got: %s\nwant: %s", got, want)
+	}
+	if loaded.Exists("stale") != 0 {
+		t.Errorf("key from before the load survived it")
+	}
+
+	list, _ := loaded.LRANGE("list", 0, -1)
+	if !reflect.DeepEqual(list, kinds) {
+		t.Errorf("list: got %#v", list)
+	}
+	for key, want := range map[string]time.Duration{"string": TTLNoExpire, "doc": time.Hour, "list": time.Hour, "hash": TTLNoExpire, "zset": 2 * time.Hour} {
+		got := loaded.TTL(key)
+		if got > 0 {
+			got = got.Round(time.Minute)
+		}
+		if got != want {
+			t.Errorf("TTL %q: got %v", key, got)
+		}
+	}
+}
+
+func TestSnapshotDropsExpiredKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.json")
+	past := time.Now().Add(-time.Minute).UnixMilli()
+	os.WriteFile(path, []byte(`{"version": 1, "keys": [
+		{"key": "gone", "type": "string", "expireAt": `+strconv.FormatInt(past, 10)+`, "value": {"val": 1, "valueType": "D"}},
+		{"key": "kept", "type": "set", "set": ["a"]}
+	]}`), 0644)
+
+	s, _ := NewStorage()
+	if err := s.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	if s.Exists("gone") != 0 || s.Exists("kept") != 1 {
+		t.Errorf("Exists gone, kept: got %d, %d", s.Exists("gone"), s.Exists("kept"))
+	}
+}
+
+func TestSnapshotMigratesLegacyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.json")
+	os.WriteFile(path, []byte(`{
+		"inner": {"name": {"val": "kv", "valueType": "S"}},
+		"list": {"list": [1, 2.5, "x"]},
+		"zset": {"board": [{"member": "ann", "score": 3}]}
+	}`), 0644)
+
+	s, _ := NewStorage()
+	if err := s.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+
+	if got := s.Get("name"); got == nil || *got != "kv" {
+		t.Errorf("Get name: got %v", got)
+	}
+	if list, _ := s.LRANGE("list", 0, -1); !reflect.DeepEqual(list, []any{1, 2.5, "x"}) {
+		t.Errorf("list: got %#v", list)
+	}
+	if score, _ := s.ZSCORE("board", "ann"); score == nil || *score != 3 {
+		t.Errorf("ZSCORE: got %v", score)
+	}
+
+	// saved again, the file is in the current format
+	s.SaveToFile(path)
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"version": 1`) {
+		t.Errorf("file was not saved in the current format: %s", data)
+	}
+}
+
+func TestSnapshotRejectsBadFiles(t *testing.T) {
+	for name, content := range map[string]string{
+		"future version": `{"version": 99, "keys": []}`,
+		"unknown type":   `{"version": 1, "keys": [{"key": "a", "type": "stream"}]}`,
+		"empty list":     `{"version": 1, "keys": [{"key": "a", "type": "list", "list": []}]}`,
+		"bad score":      `{"version": 1, "keys": [{"key": "a", "type": "zset", "zset": [{"member": "m", "score": "high"}]}]}`,
+	} {
+		path := filepath.Join(t.TempDir(), "dump.json")
+		os.WriteFile(path, []byte(content), 0644)
+
+		s, _ := NewStorage()
+		s.Set("kept", 1)
+		if err := s.LoadFromFile(path); err == nil {
+			t.Errorf("%s: file was loaded", name)
+		}
+		if s.Exists("kept") != 1 {
+			t.Errorf("%s: failed load changed the storage", name)
+		}
+	}
+}
+
+func TestSaveToFileKeepsOldFileOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dump.json")
+
+	s, _ := NewStorage()
+	s.Set("a", 1)
+	if err := s.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile: %v", err)
+	}
+	saved, _ := os.ReadFile(path)
+
+	// list elements are not checked on push, this one cannot be marshaled
+	s.RPUSH("bad", []any{func() {}})
+	if err := s.SaveToFile(path); err == nil {
+		t.Fatalf("SaveToFile of an unmarshalable element succeeded")
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != string(saved) {
+		t.Errorf("failed save changed the file:\n%s", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("failed save left %d files behind", len(entries))
+	}
+}
+
+func TestSaveToFileDuringWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.json")
+
+	s, _ := NewStorage()
+	for i := 0; i < 1000; i++ {
+		s.RPUSH("list", []any{i})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			s.RPUSH("list", []any{i})
+			s.HSET("hash", strconv.Itoa(i), i)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if err := s.SaveToFile(path); err != nil {
+			t.Fatalf("SaveToFile: %v", err)
+		}
+	}
+	<-done
+
+	loaded, _ := NewStorage()
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	n, _ := loaded.LLEN("list")
+	if fields, _ := loaded.HLEN("hash"); n < 1000 || n-1000 != fields && n-1000 != fields+1 {
+		t.Errorf("file is not one instant of the storage: %d list elements, %d fields", n, fields)
+	}
+}
+
+func TestLoadModes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.json")
+
+	src, _ := NewStorage()
+	src.Set("t1:name", "from file")
+	src.RPUSH("t1:list", []any{1, 2})
+	src.Set("t2:name", "other tenant in file")
+	if err := src.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile: %v", err)
+	}
+
+	fresh := func() *Storage {
+		s, _ := NewStorage()
+		s.Set("t1:name", "current")
+		s.Set("t1:gone", 1)
+		s.Set("t2:name", "current")
+		return &s
+	}
+
+	for _, tc := range []struct {
+		opts   LoadOptions
+		report LoadReport
+		want   map[string]any
+	}{
+		{
+			opts:   LoadOptions{Match: "t1:*"},
+			report: LoadReport{Added: []string{"t1:list"}, Overwritten: []string{"t1:name"}, Removed: []string{"t1:gone"}, Skipped: 1},
+			want:   map[string]any{"t1:name": "from file", "t1:gone": nil, "t2:name": "current"},
+		},
+		{
+			opts:   LoadOptions{Mode: LoadMergeKeep},
+			report: LoadReport{Added: []string{"t1:list"}, Kept: []string{"t1:name", "t2:name"}},
+			want:   map[string]any{"t1:name": "current", "t1:gone": 1, "t2:name": "current"},
+		},
+		{
+			opts:   LoadOptions{Mode: LoadMergeOverwrite},
+			report: LoadReport{Added: []string{"t1:list"}, Overwritten: []string{"t1:name", "t2:name"}},
+			want:   map[string]any{"t1:name": "from file", "t1:gone": 1, "t2:name": "other tenant in file"},
+		},
+	} {
+		for _, dryRun := range []bool{true, false} {
+			s := fresh()
+			opts := tc.opts
+			opts.DryRun = dryRun
+
+			report, err := s.LoadFromFileWithOptions(path, opts)
+			if err != nil {
+				t.Fatalf("%+v: %v", opts, err)
+			}
+			want := tc.report
+			want.DryRun = dryRun
+			if !reflect.DeepEqual(*report, want) {
+				t.Errorf("%+v: report got %+v, want %+v", opts, *report, want)
+			}
+
+			for key, val := range tc.want {
+				if dryRun {
+					val = *fresh().Get(key)
+				}
+				got := s.Get(key)
+				if val == nil && got != nil || val != nil && (got == nil || *got != val) {
+					t.Errorf("%+v: Get %q got %v, want %v", opts, key, got, val)
+				}
+			}
+			if n, _ := s.LLEN("t1:list"); dryRun == (n == 2) {
+				t.Errorf("%+v: LLEN t1:list got %d", opts, n)
+			}
+		}
+	}
+
+	s := fresh()
+	if _, err := s.LoadFromFileWithOptions(path, LoadOptions{Mode: "append"}); err == nil {
+		t.Errorf("unknown mode accepted")
+	}
+}
+
+func TestSnapshotViewKeepsInstant(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.Set("changed", "before")
+	s.Set("removed", 1)
+	s.RPUSH("list", []any{1})
+	s.SADD("flushed", "a")
+	want := dump(t, &s)
+
+	s.beginView()
+	s.Set("changed", "after")
+	s.Del("removed")
+	s.Set("added", 1)
+	s.RPUSH("list", []any{2})
+	s.Update(func(tx *Tx) error {
+		tx.Set("in tx", 1)
+		tx.Expire("changed", time.Hour)
+		return nil
+	})
+
+	viewed, _ := NewStorage()
+	err = s.readView(func(k *snapshotKey) error {
+		viewed.shard(k.Key).restoreKey(*k)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readView: %v", err)
+	}
+	if got := dump(t, &viewed); got != want {
+		t.Errorf("view differs from the storage when it began\n got: %s\nwant: %s", got, want)
+	}
+
+	for i, sh := range s.shards {
+		if sh.view != nil {
+			t.Errorf("shard %d kept its view", i)
+		}
+	}
+}
+
+func TestSnapshotViewAcrossFlush(t *testing.T) {
+	s, _ := NewStorage()
+	s.Set("a", 1)
+	s.HSET("b", "f", 2)
+	want := dump(t, &s)
+
+	s.beginView()
+	s.flushAll()
+	s.Set("c", 3)
+
+	viewed, _ := NewStorage()
+	s.readView(func(k *snapshotKey) error {
+		viewed.shard(k.Key).restoreKey(*k)
+		return nil
+	})
+	if got := dump(t, &viewed); got != want {
+		t.Errorf("view differs from the storage before the flush\n got: %s\nwant: %s", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"slices"
+	"sort"
+)
+
+// A view keeps the storage as it was at one instant while a snapshot is
+// written from it, without holding the shards for the whole save. Beginning
+// a view only marks every shard. From then on the first change to a key
+// copies the key as it was, and the snapshot reads either that copy or the
+// key itself, still unchanged. A shard drops its view once it has been read.
+
+// shardView holds the keys of a shard changed since its view began, as they
+// were then. A key that did not exist then is held with TypeNone.
+type shardView struct {
+	saved map[string]snapshotKey
+}
+
+// preserve copies the key before its first change since the view began. It
+// must be called with sh.mu held for writing, before the key changes.
+func (sh *shard) preserve(key string) {
+	if sh.view == nil {
+		return
+	}
+	if _, ok := sh.view.saved[key]; ok {
+		return
+	}
+
+	sh.view.saved[key] = sh.snapshotKey(key, sh.keyType(key))
+}
+
+// preserveAll preserves every key of the shard, before a change to all of
+// them. It must be called with sh.mu held for writing.
+func (sh *shard) preserveAll() {
+	if sh.view == nil {
+		return
+	}
+
+	sh.eachKey(func(key string, _ KeyType) {
+		sh.preserve(key)
+	})
+}
+
+// viewKey returns the key as it was when the view began, and false if it did
+// not exist or has expired since. It must be called with sh.mu held.
+func (sh *shard) viewKey(key string) (snapshotKey, bool) {
+	if k, ok := sh.view.saved[key]; ok {
+		return k, k.Type != TypeNone && !k.expired(sh.clock.Now().UnixMilli())
+	}
+
+	typ := sh.keyType(key)
+	if typ == TypeNone || sh.isExpired(key) {
+		return snapshotKey{}, false
+	}
+	return sh.snapshotKey(key, typ), true
+}
+
+// beginView marks every shard so that its keys are kept as they are now until
+// readView has read them. It returns the changes not yet in a snapshot at
+// that instant. Views are read one at a time, under saveMu.
+func (s *Storage) beginView() int64 {
+	defer s.lockAll()()
+
+	s.markView()
+	return s.dirty.Load()
+}
+
+// markView is beginView for callers that have every shard locked already.
+func (s *Storage) markView() {
+	for _, sh := range s.shards {
+		sh.view = &shardView{saved: make(map[string]snapshotKey)}
+	}
+}
+
+// readView calls f with every key of the storage as it was when beginView
+// was called, one shard at a time and each shard sorted by key, and ends the
+// view of every shard. f is called with no shard locked.
+func (s *Storage) readView(f func(k *snapshotKey) error) error {
+	defer s.endView()
+
+	for _, sh := range s.shards {
+		if err := s.readShardView(sh, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) readShardView(sh *shard, f func(k *snapshotKey) error) error {
+	unlock := s.rlockShard(sh)
+	var keys []string
+	sh.eachKey(func(key string, _ KeyType) {
+		keys = append(keys, key)
+	})
+	unlock()
+	sort.Strings(keys)
+
+	// the shard is locked for one key at a time, so that writers wait for
+	// the copy of a key at most
+	for _, key := range keys {
+		unlock := s.rlockShard(sh)
+		k, ok := sh.viewKey(key)
+		unlock()
+
+		if !ok {
+			continue
+		}
+		if err := f(&k); err != nil {
+			return err
+		}
+	}
+
+	// keys removed since the view began are only left in the view
+	unlock = s.lockShard(sh)
+	now := sh.clock.Now().UnixMilli()
+	var removed []snapshotKey
+	for key, k := range sh.view.saved {
+		if _, found := slices.BinarySearch(keys, key); !found && k.Type != TypeNone && !k.expired(now) {
+			removed = append(removed, k)
+		}
+	}
+	sh.view = nil
+	unlock()
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Key < removed[j].Key })
+
+	for i := range removed {
+		if err := f(&removed[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endView drops the views readView did not get to.
+func (s *Storage) endView() {
+	for _, sh := range s.shards {
+		unlock := s.lockShard(sh)
+		sh.view = nil
+		unlock()
+	}
+}
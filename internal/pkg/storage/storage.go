@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"sync"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 )
@@ -12,6 +14,10 @@ type Kind string
 const (
 	kindInt      Kind = "D"
 	kindString   Kind = "S"
+	kindFloat    Kind = "F"
+	kindBool     Kind = "B"
+	kindNull     Kind = "N"
+	kindJSON     Kind = "J"
 	kindUndefind Kind = "UND"
 )
 
@@ -25,35 +31,71 @@ type List struct {
 }
 
 type Storage struct {
-	inner       map[string]Value
-	list        map[string]*List
-	innerMap    map[string]map[string]Value
-	logger      *zap.Logger
-	mu          *sync.Mutex
-	innerExpire map[string]int64
+	shards  []*shard
+	logger  *zap.Logger
+	clock   Clock
+	waitMu  *sync.Mutex
+	waiters map[string][]*waiter
+	sweeper *expirySweeper
+	aof     *appendOnlyFile
+	// dirty counts the changes made since the last snapshot was saved.
+	dirty *atomic.Int64
+	// saveMu lets one snapshot be saved or one append-only file be
+	// rewritten at a time, as they share the views of the shards.
+	saveMu    *sync.Mutex
+	snapshots *snapshotScheduler
+	life      *lifecycle
+	// tx is set on the view a transaction runs against, see Update.
+	tx *txState
 }
 
-func NewStorage() (Storage, error) {
-	logger, err := zap.NewProduction()
+// NewStorage returns an empty storage, or one restored from the persistence
+// files opts give it. Close stops its background work.
+func NewStorage(opts ...Option) (Storage, error) {
+	o, err := newOptions(opts)
 	if err != nil {
 		return Storage{}, err
 	}
-	defer logger.Sync()
-	logger.Info("storage created")
-
-	return Storage{
-		inner:       make(map[string]Value),
-		list:        make(map[string]*List),
-		innerMap:    make(map[string]map[string]Value),
-		logger:      logger,
-		mu:          new(sync.Mutex),
-		innerExpire: make(map[string]int64),
-	}, nil
+
+	s := Storage{
+		shards:    make([]*shard, o.shardCount),
+		logger:    o.logger,
+		clock:     o.clock,
+		waitMu:    new(sync.Mutex),
+		waiters:   make(map[string][]*waiter),
+		sweeper:   newExpirySweeper(),
+		aof:       newAppendOnlyFile(o.logger),
+		dirty:     new(atomic.Int64),
+		saveMu:    new(sync.Mutex),
+		snapshots: new(snapshotScheduler),
+		life:      &lifecycle{stop: make(chan struct{})},
+	}
+	for i := range s.shards {
+		s.shards[i] = newShard(o.logger, o.clock)
+	}
+	go s.sweepExpired()
+
+	if err := s.openPersistence(o); err != nil {
+		s.Close(context.Background())
+		return Storage{}, err
+	}
+	if o.memoryLimit > 0 {
+		s.checkHeap(o.memoryLimit)
+		s.life.wg.Add(1)
+		go s.watchMemory(o.memoryLimit)
+	}
+
+	s.logger.Info("storage created", zap.Int("shards", o.shardCount))
+	return s, nil
 }
 
 func (r Storage) HSET(key string, field string, value any) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	if err := r.checkMemory(); err != nil {
+		return err
+	}
+
+	sh, unlock := r.lockKey(key)
+	defer unlock()
 
 	newVal, err := newValue(value)
 	if err != nil {
@@ -61,20 +103,25 @@ func (r Storage) HSET(key string, field string, value any) error {
 		return err
 	}
 
-	_, ok := r.innerMap[key]
+	if err := sh.checkType(key, TypeHash); err != nil {
+		return err
+	}
+
+	_, ok := sh.innerMap[key]
 	if !ok {
-		r.innerMap[key] = make(map[string]Value)
+		sh.innerMap[key] = make(map[string]Value)
 	}
-	r.innerMap[key][field] = newVal
-	r.innerExpire[key] = 0
+	sh.innerMap[key][field] = newVal
+	sh.bumpVersion(key)
+	r.propagate("HSET", key, field, newVal.Val)
 	return nil
 }
 
 func (r Storage) HGET(key string, field string) *any {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	sh, unlock := r.rlockKey(key)
+	defer unlock()
 
-	res, ok := r.hget(key, field)
+	res, ok := sh.hget(key, field)
 
 	if !ok {
 		r.logger.Error("KeyError",
@@ -87,9 +134,12 @@ func (r Storage) HGET(key string, field string) *any {
 }
 
 func (r Storage) Set(key string, value any) error {
+	if err := r.checkMemory(); err != nil {
+		return err
+	}
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	sh, unlock := r.lockKey(key)
+	defer unlock()
 
 	var val Value
 
@@ -98,7 +148,14 @@ func (r Storage) Set(key string, value any) error {
 		return err
 	}
 
-	r.inner[key] = val
+	if err := sh.checkType(key, TypeString); err != nil {
+		return err
+	}
+
+	sh.inner[key] = val
+	delete(sh.innerExpire, key)
+	sh.bumpVersion(key)
+	r.propagateSet(sh, key)
 	r.logger.Info("value set",
 		zap.String("key", key),
 		zap.String("value", string(val.ValueType)))
@@ -109,10 +166,10 @@ func (r Storage) Set(key string, value any) error {
 }
 
 func (r Storage) Get(key string) *any {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	sh, unlock := r.rlockKey(key)
+	defer unlock()
 
-	result, ok := r.inner[key]
+	result, ok := sh.inner[key]
 	if !ok {
 		return nil
 	}
@@ -126,9 +183,9 @@ func (r Storage) Get(key string) *any {
 }
 
 func (r Storage) GetType(key string) any {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	result, ok := r.inner[key]
+	sh, unlock := r.rlockKey(key)
+	defer unlock()
+	result, ok := sh.inner[key]
 	if !ok {
 		return "No"
 	}
@@ -137,63 +194,91 @@ func (r Storage) GetType(key string) any {
 }
 
 func (s *Storage) LPUSH(key string, elements []any) error {
+	if err := s.checkMemory(); err != nil {
+		return err
+	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.serveWaiters(key)
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeList); err != nil {
+		return err
+	}
 
 	if len(elements) == 0 {
 		return errors.New("WrongArgs")
 	}
 
-	if _, exists := s.list[key]; !exists {
-		s.list[key] = &List{}
+	if _, exists := sh.list[key]; !exists {
+		sh.list[key] = &List{}
 	}
 
-	list := s.list[key]
+	list := sh.list[key]
 	for i := len(elements) - 1; i >= 0; i-- {
 		list.Elem = append([]any{elements[i]}, list.Elem...)
 	}
+	sh.bumpVersion(key)
+	s.propagate(append([]any{"LPUSH", key}, elements...)...)
 
 	s.logger.Info("LPUSH executed")
 	return nil
 }
 
 func (s *Storage) RPUSH(key string, elements []any) error {
+	if err := s.checkMemory(); err != nil {
+		return err
+	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.serveWaiters(key)
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeList); err != nil {
+		return err
+	}
 
 	if len(elements) == 0 {
 		return errors.New("WrongArgs")
 	}
 
-	if _, exist := s.list[key]; !exist {
-		s.list[key] = &List{}
+	if _, exist := sh.list[key]; !exist {
+		sh.list[key] = &List{}
 	}
 
-	list := s.list[key]
+	list := sh.list[key]
 	for i := 0; i < len(elements); i++ {
 		list.Elem = append(list.Elem, elements[i])
 	}
+	sh.bumpVersion(key)
+	s.propagate(append([]any{"RPUSH", key}, elements...)...)
 
 	s.logger.Info("RPUSH executed")
 	return nil
 }
 
 func (s *Storage) RADDTOSET(key string, elements []any) error {
+	if err := s.checkMemory(); err != nil {
+		return err
+	}
+
+	defer s.serveWaiters(key)
+	sh, unlock := s.lockKey(key)
+	defer unlock()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if err := sh.checkType(key, TypeList); err != nil {
+		return err
+	}
 
 	if len(elements) == 0 {
 		return errors.New("WrongArgs")
 	}
 
-	if _, exist := s.list[key]; !exist {
-		s.list[key] = &List{Elem: make([]any, 0)}
+	if _, exist := sh.list[key]; !exist {
+		sh.list[key] = &List{Elem: make([]any, 0)}
 	}
 
-	list := s.list[key]
+	list := sh.list[key]
 	existing := make(map[any]bool)
 
 	for _, elem := range list.Elem {
@@ -206,20 +291,33 @@ func (s *Storage) RADDTOSET(key string, elements []any) error {
 			existing[elem] = true
 		}
 	}
+	sh.bumpVersion(key)
+	s.propagate(append([]any{"RADDTOSET", key}, elements...)...)
 
 	s.logger.Info("RADDTOSET executed")
 	return nil
 }
 
-func (s *Storage) LPOP(key string, count ...int) ([]any, error) {
+func (s *Storage) LPOP(key string, count ...int) (_ []any, err error) {
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	sh, unlock := s.lockKey(key)
+	defer unlock()
 
-	list, exist := s.list[key]
+	if err := sh.checkType(key, TypeList); err != nil {
+		return nil, err
+	}
+	defer sh.removeIfEmpty(key)
+
+	list, exist := sh.list[key]
 	if !exist || len(list.Elem) == 0 {
 		return nil, errors.New("list is empty or does not exist")
 	}
+	defer func() {
+		if err == nil {
+			sh.bumpVersion(key)
+			s.propagate(append([]any{"LPOP", key}, intArgs(count)...)...)
+		}
+	}()
 
 	if len(count) > 2 {
 		return nil, errors.New("WrongArgs")
@@ -262,16 +360,26 @@ func (s *Storage) LPOP(key string, count ...int) ([]any, error) {
 
 }
 
+func (s *Storage) RPOP(key string, count ...int) (_ []any, err error) {
 
-func (s *Storage) RPOP(key string, count ...int) ([]any, error) {
+	sh, unlock := s.lockKey(key)
+	defer unlock()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if err := sh.checkType(key, TypeList); err != nil {
+		return nil, err
+	}
+	defer sh.removeIfEmpty(key)
 
-	list, exist := s.list[key]
+	list, exist := sh.list[key]
 	if !exist || len(list.Elem) == 0 {
 		return nil, errors.New("list is empty or does not exist")
 	}
+	defer func() {
+		if err == nil {
+			sh.bumpVersion(key)
+			s.propagate(append([]any{"RPOP", key}, intArgs(count)...)...)
+		}
+	}()
 
 	if len(count) == 0 {
 		lastIdx := len(list.Elem) - 1
@@ -319,13 +427,19 @@ func (s *Storage) RPOP(key string, count ...int) ([]any, error) {
 	return nil, errors.New("wrong number of arguments")
 }
 
-
 func (s *Storage) LSET(key string, index int, element any) (any, error) {
+	if err := s.checkMemory(); err != nil {
+		return nil, err
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if err := sh.checkType(key, TypeList); err != nil {
+		return "", err
+	}
 
-	list, exist := s.list[key]
+	list, exist := sh.list[key]
 	if !exist {
 		return "", errors.New("WrongArgs")
 	}
@@ -339,6 +453,8 @@ func (s *Storage) LSET(key string, index int, element any) (any, error) {
 	}
 
 	list.Elem[index] = element
+	sh.bumpVersion(key)
+	s.propagate("LSET", key, index, element)
 	s.logger.Info("LSET executed")
 
 	return "OK", nil
@@ -347,10 +463,14 @@ func (s *Storage) LSET(key string, index int, element any) (any, error) {
 
 func (s *Storage) LGET(key string, index int) (any, error) {
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeList); err != nil {
+		return "", err
+	}
 
-	list, exist := s.list[key]
+	list, exist := sh.list[key]
 	if !exist {
 		return "", errors.New("WrongArgs")
 	}
@@ -56,7 +56,7 @@ func TestKind(t *testing.T) {
 			var kind Kind
 			kind = getType(c.value)
 
-			if s.inner[c.key].ValueType != kind {
+			if s.shard(c.key).inner[c.key].ValueType != kind {
 				t.Errorf("kinds not equal")
 			}
 		})
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"errors"
+	"maps"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+// ErrInTransaction is returned by operations that cannot run inside a transaction.
+var ErrInTransaction = errors.New("operation not allowed inside a transaction")
+
+// Tx is the view of a Storage a transaction runs against. It has every
+// command of Storage and sees its own changes right away. Blocking pops do not
+// wait inside a transaction, they return nil at once if no list has elements.
+//
+// A Tx must not be used once the function given to Update has returned.
+type Tx struct {
+	Storage
+}
+
+// txState records what a transaction needs to roll back or to finish on commit.
+type txState struct {
+	undo    map[string]keySnapshot
+	pushed  []string
+	records [][]any
+}
+
+// keySnapshot is the state of a key before a transaction first wrote to it.
+type keySnapshot struct {
+	typ       KeyType
+	inner     Value
+	list      []any
+	hash      map[string]Value
+	set       map[string]struct{}
+	zset      []ZMember
+	expireAt  int64
+	hasExpire bool
+	version   uint64
+}
+
+// Update runs fn as a transaction. Every shard stays locked while fn runs, so
+// other clients see either all of its changes or none of them. If fn returns
+// an error or panics, all of its changes are rolled back. Calling Update on a
+// Tx joins the running transaction.
+func (s *Storage) Update(fn func(tx *Tx) error) error {
+	if s.tx != nil {
+		return fn(&Tx{Storage: *s})
+	}
+
+	unlock := s.lockAll()
+	tx := &Tx{Storage: *s}
+	tx.tx = &txState{undo: make(map[string]keySnapshot)}
+
+	committed := false
+	defer func() {
+		if !committed {
+			tx.rollback()
+			unlock()
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		s.logger.Info("transaction rolled back", zap.Error(err))
+		return err
+	}
+
+	// logged before the shards are unlocked, so that no later change to
+	// the same keys can reach the file first
+	s.aof.appendTx(tx.tx.records)
+
+	committed = true
+	unlock()
+
+	for _, key := range tx.tx.pushed {
+		s.serveWaiters(key)
+	}
+
+	s.logger.Info("transaction committed", zap.Int("keys", len(tx.tx.undo)))
+	return nil
+}
+
+// touch drops the key if it has expired and, the first time the transaction
+// is about to write to it, records its state.
+func (t *txState) touch(sh *shard, key string) {
+	sh.expireIfNeeded(key)
+	sh.preserve(key)
+
+	if _, ok := t.undo[key]; !ok {
+		t.undo[key] = sh.snapshot(key)
+	}
+}
+
+// rollback puts back every key the transaction wrote to.
+func (tx *Tx) rollback() {
+	for key, snap := range tx.tx.undo {
+		tx.shard(key).restore(key, snap)
+	}
+}
+
+// snapshot must be called with sh.mu held.
+func (sh *shard) snapshot(key string) keySnapshot {
+	snap := keySnapshot{typ: sh.keyType(key)}
+
+	switch snap.typ {
+	case TypeString:
+		// documents are replaced on change, never changed in place
+		snap.inner = sh.inner[key]
+	case TypeList:
+		snap.list = slices.Clone(sh.list[key].Elem)
+	case TypeHash:
+		snap.hash = maps.Clone(sh.innerMap[key])
+	case TypeSet:
+		snap.set = maps.Clone(sh.sets[key])
+	case TypeZSet:
+		snap.zset = sh.zsets[key].members()
+	}
+
+	snap.expireAt, snap.hasExpire = sh.innerExpire[key]
+	snap.version = sh.versions[key]
+	return snap
+}
+
+// restore must be called with sh.mu held.
+func (sh *shard) restore(key string, snap keySnapshot) {
+	sh.removeKey(key)
+
+	switch snap.typ {
+	case TypeString:
+		sh.inner[key] = snap.inner
+	case TypeList:
+		sh.list[key] = &List{Elem: snap.list}
+	case TypeHash:
+		sh.innerMap[key] = snap.hash
+	case TypeSet:
+		sh.sets[key] = snap.set
+	case TypeZSet:
+		zs := newZSet()
+		for _, m := range snap.zset {
+			zs.set(m.Member, m.Score)
+		}
+		sh.zsets[key] = zs
+	}
+
+	if snap.hasExpire {
+		sh.innerExpire[key] = snap.expireAt
+	}
+	if snap.typ != TypeNone {
+		sh.versions[key] = snap.version
+	}
+}
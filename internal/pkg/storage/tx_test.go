@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateCommits(t *testing.T) {
+	s, _ := NewStorage()
+
+	err := s.Update(func(tx *Tx) error {
+		if err := tx.HSET("order:42", "total", 99); err != nil {
+			return err
+		}
+		if err := tx.RPUSH("queue", []any{"order:42"}); err != nil {
+			return err
+		}
+
+		// the transaction sees its own writes
+		if v := tx.HGET("order:42", "total"); v == nil || *v != 99 {
+			t.Errorf("HGET inside the transaction = %v, want 99", v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	if v := s.HGET("order:42", "total"); v == nil || *v != 99 {
+		t.Errorf("HGET after commit = %v, want 99", v)
+	}
+	if elems, _ := s.LRANGE("queue", 0, -1); !reflect.DeepEqual(elems, []any{"order:42"}) {
+		t.Errorf("queue after commit = %v", elems)
+	}
+}
+
+func TestUpdateRollsBack(t *testing.T) {
+	s, _ := NewStorage()
+	s.HSET("order:1", "total", 10)
+	s.RPUSH("queue", []any{"order:1"})
+	s.SADD("tags", "a")
+	s.ZADD("board", ZMember{"ann", 1})
+	s.Set("counter", 5)
+	s.Expire("counter", time.Hour)
+
+	errBoom := errors.New("boom")
+	err := s.Update(func(tx *Tx) error {
+		tx.HSET("order:1", "total", 20)
+		tx.HSET("order:2", "total", 30)
+		tx.RPUSH("queue", []any{"order:2"})
+		tx.LSET("queue", 0, "changed")
+		tx.SADD("tags", "b")
+		tx.ZINCRBY("board", 5, "ann")
+		tx.Incr("counter")
+		tx.Persist("counter")
+		tx.Del("tags")
+		return errBoom
+	})
+	if err != errBoom {
+		t.Fatalf("Update error = %v, want errBoom", err)
+	}
+
+	if v := s.HGET("order:1", "total"); v == nil || *v != 10 {
+		t.Errorf("order:1 total = %v, want 10", v)
+	}
+	if s.Exists("order:2") != 0 {
+		t.Errorf("key created by the rolled back transaction still exists")
+	}
+	if elems, _ := s.LRANGE("queue", 0, -1); !reflect.DeepEqual(elems, []any{"order:1"}) {
+		t.Errorf("queue = %v, want [order:1]", elems)
+	}
+	if members, _ := s.SMEMBERS("tags"); !reflect.DeepEqual(members, []string{"a"}) {
+		t.Errorf("tags = %v, want [a]", members)
+	}
+	if score, _ := s.ZSCORE("board", "ann"); score == nil || *score != 1 {
+		t.Errorf("ann score = %v, want 1", score)
+	}
+	if v := s.Get("counter"); v == nil || *v != 5 {
+		t.Errorf("counter = %v, want 5", v)
+	}
+	if ttl := s.TTL("counter"); ttl <= 0 {
+		t.Errorf("counter lost its deadline, TTL = %v", ttl)
+	}
+}
+
+func TestUpdateRollsBackOnPanic(t *testing.T) {
+	s, _ := NewStorage()
+	s.Set("key", "before")
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("the panic did not reach the caller")
+			}
+		}()
+		s.Update(func(tx *Tx) error {
+			tx.Set("key", "after")
+			panic("boom")
+		})
+	}()
+
+	if v := s.Get("key"); v == nil || *v != "before" {
+		t.Errorf("key = %v, want before", v)
+	}
+	// the shards must have been unlocked
+	s.Set("other", 1)
+}
+
+func TestUpdateIsAtomic(t *testing.T) {
+	s, _ := NewStorage()
+	s.Set("a", 0)
+	s.Set("b", 0)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			s.Update(func(tx *Tx) error {
+				tx.Incr("a")
+				tx.Incr("b")
+				return nil
+			})
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		var a, b any
+		s.Update(func(tx *Tx) error {
+			a, b = *tx.Get("a"), *tx.Get("b")
+			return nil
+		})
+		if a != b {
+			t.Fatalf("saw a = %v and b = %v in the middle of a transaction", a, b)
+		}
+	}
+	wg.Wait()
+}
+
+func TestUpdateServesWaitersOnCommit(t *testing.T) {
+	s, _ := NewStorage()
+
+	popped := make(chan *PoppedElem, 1)
+	go func() {
+		elem, _ := s.BLPop(context.Background(), []string{"jobs"}, time.Second)
+		popped <- elem
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	s.Update(func(tx *Tx) error {
+		tx.RPUSH("jobs", []any{"rolled back"})
+		return errors.New("abort")
+	})
+	s.Update(func(tx *Tx) error {
+		// blocking pops do not wait inside a transaction
+		if elem, err := tx.BLPop(context.Background(), []string{"empty"}, 0); elem != nil || err != nil {
+			t.Errorf("BLPop inside a transaction = %v, %v", elem, err)
+		}
+		return tx.RPUSH("jobs", []any{"committed"})
+	})
+
+	select {
+	case elem := <-popped:
+		if elem == nil || elem.Value != "committed" {
+			t.Errorf("blocked client got %v, want committed", elem)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("blocked client was not served after the commit")
+	}
+}
+
+func TestNestedUpdate(t *testing.T) {
+	s, _ := NewStorage()
+
+	errInner := errors.New("inner")
+	err := s.Update(func(tx *Tx) error {
+		tx.Set("outer", 1)
+		return tx.Update(func(inner *Tx) error {
+			inner.Set("inner", 1)
+			return errInner
+		})
+	})
+	if err != errInner {
+		t.Fatalf("Update error = %v, want errInner", err)
+	}
+
+	if s.Exists("outer", "inner") != 0 {
+		t.Errorf("nested transaction was not rolled back with the outer one")
+	}
+
+	s.Update(func(tx *Tx) error {
+		if err := tx.LoadFromFile("missing.json"); err != ErrInTransaction {
+			t.Errorf("LoadFromFile inside a transaction error = %v", err)
+		}
+		return nil
+	})
+}
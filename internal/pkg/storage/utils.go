@@ -3,54 +3,150 @@ package storage
 import (
 	"errors"
 	"math"
+	"reflect"
 )
-// used to set values to a structure 
+
+// used to set values to a structure
 func newValue(val any) (Value, error) {
 	valueType := getType(val)
-	if valueType != kindUndefind {
-		return Value{
-			Val:       val,
-			ValueType: valueType,
-		}, nil
+	switch valueType {
+	case kindUndefind:
+		return Value{}, errors.New("Undefined ValueType")
+	case kindJSON:
+		// the caller keeps its own copy of the document
+		val = cloneJSON(val)
 	}
-	return Value{}, errors.New("Undefined ValueType")
+
+	return Value{
+		Val:       val,
+		ValueType: valueType,
+	}, nil
 }
 
 // used to set const to a value's type
 func getType(val any) Kind {
-	switch val.(type) {
-	case int:
+	switch v := val.(type) {
+	case int, int64:
 		return kindInt
 	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return kindUndefind
+		}
 		if isFloatInt(val) {
 			return kindInt
 		}
-		return kindUndefind
+		return kindFloat
 	case string:
 		return kindString
+	case bool:
+		return kindBool
+	case nil:
+		return kindNull
+	case map[string]any, []any:
+		if isJSONDocument(val) {
+			return kindJSON
+		}
+		return kindUndefind
 	default:
 		return kindUndefind
 	}
 }
 
+// used to check that everything nested in an object or array has a kind
+func isJSONDocument(val any) bool {
+	switch v := val.(type) {
+	case map[string]any:
+		for _, elem := range v {
+			if getType(elem) == kindUndefind {
+				return false
+			}
+		}
+	case []any:
+		for _, elem := range v {
+			if getType(elem) == kindUndefind {
+				return false
+			}
+		}
+	}
 
+	return true
+}
+
+// used to deep copy objects and arrays of a kindJSON value
+func cloneJSON(val any) any {
+	switch v := val.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(v))
+		for k, elem := range v {
+			res[k] = cloneJSON(elem)
+		}
+		return res
+	case []any:
+		res := make([]any, len(v))
+		for i, elem := range v {
+			res[i] = cloneJSON(elem)
+		}
+		return res
+	}
+
+	return val
+}
+
+// isFloatInt reports whether num is a whole number an int can hold. The
+// upper bound is exclusive, float64(math.MaxInt) rounds up to one past it.
 func isFloatInt(num any) bool {
-	return num.(float64) == math.Trunc(num.(float64))
+	f := num.(float64)
+	return f == math.Trunc(f) && f >= math.MinInt && f < math.MaxInt
 }
 
-// subfunction for HGET
-func (r *Storage) hget(key string, field string) (Value, bool) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// used to read a kindInt value as int
+func toInt(val Value) (int, bool) {
+	if val.ValueType != kindInt {
+		return 0, false
+	}
 
-	res, ok := r.innerMap[key][field]
+	switch n := val.Val.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		if !isFloatInt(n) {
+			return 0, false
+		}
+		return int(n), true
+	}
+
+	return 0, false
+}
+
+// used to read a kindInt or kindFloat value as float64
+func toFloat(val Value) (float64, bool) {
+	if val.ValueType != kindInt && val.ValueType != kindFloat {
+		return 0, false
+	}
+
+	switch n := val.Val.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
+
+// subfunction for HGET, must be called with sh.mu held
+func (sh *shard) hget(key string, field string) (Value, bool) {
+	res, ok := sh.innerMap[key][field]
 	if !ok {
 		return Value{}, false
 	}
 	return res, true
 }
 
-
 // using for function LPOP
 func convertIndex(index int, length int) int {
 	if index < 0 {
@@ -76,4 +172,44 @@ func reverse(slice []any) {
 	for i, j := 0, len(slice)-1; i < j; i, j = i+1, j-1 {
 		slice[i], slice[j] = slice[j], slice[i]
 	}
-}
\ No newline at end of file
+}
+
+// used to clamp an inclusive LRANGE/LTRIM range, false if it selects nothing
+func listRange(start, stop, length int) (int, int, bool) {
+	start, stop = convertIndex(start, length), convertIndex(stop, length)
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+
+	if start > stop || start >= length {
+		return 0, 0, false
+	}
+
+	return start, stop, true
+}
+
+// used to compare list elements, so that 1 and 1.0 decoded from JSON are equal
+func sameElem(a, b any) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func number(val any) (float64, bool) {
+	switch n := val.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
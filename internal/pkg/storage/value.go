@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// UnmarshalJSON restores a value saved by SaveToFile. Numbers are decoded
+// exactly, so integers do not come back as rounded float64, and the kind found
+// in the file must match the one of the decoded value.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Val       json.RawMessage `json:"val"`
+		ValueType Kind            `json:"valueType"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	val, err := decodeJSONValue(raw.Val)
+	if err != nil {
+		return err
+	}
+
+	restored, err := newValue(val)
+	if err != nil {
+		return err
+	}
+
+	if raw.ValueType != "" && raw.ValueType != restored.ValueType {
+		return fmt.Errorf("value %s does not match kind %s", raw.Val, raw.ValueType)
+	}
+
+	*v = restored
+	return nil
+}
+
+// decodeJSONValue decodes a JSON document keeping whole numbers as int.
+func decodeJSONValue(data []byte) (any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var val any
+	if err := dec.Decode(&val); err != nil {
+		return nil, err
+	}
+
+	return restoreNumbers(val), nil
+}
+
+// restoreNumbers replaces the json.Number values left by a decoder with
+// UseNumber by int when they are whole and fit, float64 otherwise.
+func restoreNumbers(val any) any {
+	switch v := val.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil && int64(int(n)) == n {
+			return int(n)
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, elem := range v {
+			v[k] = restoreNumbers(elem)
+		}
+	case []any:
+		for i, elem := range v {
+			v[i] = restoreNumbers(elem)
+		}
+	}
+
+	return val
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValueKinds(t *testing.T) {
+	cases := []struct {
+		name  string
+		value any
+		kind  Kind
+	}{
+		{"int", 42, kindInt},
+		{"int64", int64(42), kindInt},
+		{"whole float", 42.0, kindInt},
+		{"float", 4.2, kindFloat},
+		{"NaN", math.NaN(), kindUndefind},
+		{"Inf", math.Inf(1), kindUndefind},
+		{"string", "str", kindString},
+		{"bool", false, kindBool},
+		{"null", nil, kindNull},
+		{"object", map[string]any{"a": 1.5, "b": []any{"x", nil}}, kindJSON},
+		{"array", []any{1, "two", true}, kindJSON},
+		{"nested NaN", map[string]any{"a": []any{math.NaN()}}, kindUndefind},
+		{"struct", struct{}{}, kindUndefind},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if kind := getType(c.value); kind != c.kind {
+				t.Errorf("getType(%v) = %q, want %q", c.value, kind, c.kind)
+			}
+		})
+	}
+}
+
+func TestSetJSONDocumentIsCopied(t *testing.T) {
+	s, _ := NewStorage()
+
+	doc := map[string]any{"tags": []any{"a"}}
+	if err := s.Set("doc", doc); err != nil {
+		t.Fatal(err)
+	}
+	doc["tags"].([]any)[0] = "changed"
+
+	got := (*s.Get("doc")).(map[string]any)
+	if got["tags"].([]any)[0] != "a" {
+		t.Errorf("stored document changed with the caller's copy: %v", got)
+	}
+}
+
+func TestSaveLoadKinds(t *testing.T) {
+	values := map[string]any{
+		"int":    1 << 60,
+		"float":  2.5,
+		"string": "str",
+		"bool":   true,
+		"null":   nil,
+		"doc":    map[string]any{"n": 3, "list": []any{1.5, "x", false}},
+	}
+
+	s, _ := NewStorage()
+	for k, v := range values {
+		if err := s.Set(k, v); err != nil {
+			t.Fatalf("Set(%q): %v", k, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "storage.json")
+	if err := s.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, _ := NewStorage()
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	for k, want := range values {
+		got := loaded.Get(k)
+		if got == nil {
+			t.Errorf("%q is missing after load", k)
+			continue
+		}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("%q = %#v after load, want %#v", k, *got, want)
+		}
+		if loaded.shard(k).inner[k].ValueType != s.shard(k).inner[k].ValueType {
+			t.Errorf("%q kind = %q after load, want %q", k, loaded.shard(k).inner[k].ValueType, s.shard(k).inner[k].ValueType)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// ErrVersionMismatch is returned by SetIfVersion when the key has changed
+// since the client read it.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// Version returns the version of the key whatever its type, 0 if it does not
+// exist. Every change to a key gives it a greater version than it ever had,
+// including a key deleted and created again.
+func (s *Storage) Version(key string) uint64 {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	return sh.versions[key]
+}
+
+// GetWithVersion is Get returning the version of the value with it.
+func (s *Storage) GetWithVersion(key string) (*any, uint64) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	result, ok := sh.inner[key]
+	if !ok {
+		return nil, 0
+	}
+
+	return &result.Val, sh.versions[key]
+}
+
+// SetIfVersion is Set done only if the key is still at version, and returns
+// the new version. Version 0 stands for a key that does not exist, so that
+// clients can create a key without overwriting one made in the meantime.
+func (s *Storage) SetIfVersion(key string, value any, version uint64) (uint64, error) {
+	if err := s.checkMemory(); err != nil {
+		return 0, err
+	}
+
+	val, err := newValue(value)
+	if err != nil {
+		return 0, err
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return 0, err
+	}
+	if sh.versions[key] != version {
+		return 0, ErrVersionMismatch
+	}
+
+	sh.inner[key] = val
+	delete(sh.innerExpire, key)
+	sh.bumpVersion(key)
+	s.propagateSet(sh, key)
+
+	s.logger.Info("value set if version",
+		zap.String("key", key),
+		zap.Uint64("version", sh.versions[key]))
+	return sh.versions[key], nil
+}
+
+// CompareAndSwap sets the key to new if it holds old and reports whether it
+// did. Numbers compare by value whatever their Go type, documents compare
+// deeply. A key that does not exist holds nothing, not even null.
+func (s *Storage) CompareAndSwap(key string, old, new any) (bool, error) {
+	if err := s.checkMemory(); err != nil {
+		return false, err
+	}
+
+	oldVal, err := newValue(old)
+	if err != nil {
+		return false, err
+	}
+	newVal, err := newValue(new)
+	if err != nil {
+		return false, err
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeString); err != nil {
+		return false, err
+	}
+
+	current, ok := sh.inner[key]
+	if !ok || current.ValueType != oldVal.ValueType || !jsonEqual(current.Val, oldVal.Val) {
+		return false, nil
+	}
+
+	sh.inner[key] = newVal
+	delete(sh.innerExpire, key)
+	sh.bumpVersion(key)
+	s.propagateSet(sh, key)
+
+	s.logger.Info("CAS executed", zap.String("key", key))
+	return true, nil
+}
+
+// bumpVersion gives the key a new version, or forgets its version if the
+// change removed it. It must be called with sh.mu held after every change to
+// the key, its deadline included.
+func (sh *shard) bumpVersion(key string) {
+	if !sh.exists(key) {
+		delete(sh.versions, key)
+		return
+	}
+
+	sh.revision++
+	sh.versions[key] = sh.revision
+}
+
+// jsonEqual compares two values of a kind, numbers by value.
+func jsonEqual(a, b any) bool {
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, elem := range x {
+			other, ok := y[k]
+			if !ok || !jsonEqual(elem, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+
+	return a == b
+}
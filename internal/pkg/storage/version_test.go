@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVersions(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	if v := s.Version("key"); v != 0 {
+		t.Errorf("version of a missing key: got %d", v)
+	}
+
+	s.Set("key", "a")
+	val, v1 := s.GetWithVersion("key")
+	if val == nil || *val != "a" || v1 == 0 {
+		t.Fatalf("GetWithVersion: got %v, %d", val, v1)
+	}
+
+	s.Get("key")
+	if v := s.Version("key"); v != v1 {
+		t.Errorf("a read changed the version from %d to %d", v1, v)
+	}
+
+	s.Expire("key", time.Hour)
+	v2 := s.Version("key")
+	if v2 <= v1 {
+		t.Errorf("Expire did not bump the version: %d then %d", v1, v2)
+	}
+
+	s.Del("key")
+	if v := s.Version("key"); v != 0 {
+		t.Errorf("version of a deleted key: got %d", v)
+	}
+	s.Set("key", "b")
+	if v := s.Version("key"); v <= v2 {
+		t.Errorf("a key created again went back to version %d from %d", v, v2)
+	}
+
+	// failed and no-op commands leave the version alone
+	s.RPUSH("list", []any{1, 2})
+	v3 := s.Version("list")
+	s.LREM("list", 0, 3)
+	s.Set("list", "x")
+	if v := s.Version("list"); v != v3 {
+		t.Errorf("version moved from %d to %d without a change", v3, v)
+	}
+	s.LPOP("list")
+	if v := s.Version("list"); v <= v3 {
+		t.Errorf("LPOP did not bump the version: %d then %d", v3, v)
+	}
+}
+
+func TestSetIfVersion(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	v1, err := s.SetIfVersion("key", "a", 0)
+	if err != nil || v1 == 0 {
+		t.Fatalf("creating with version 0: got %d, %v", v1, err)
+	}
+	if _, err := s.SetIfVersion("key", "b", 0); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("creating an existing key: got %v", err)
+	}
+
+	v2, err := s.SetIfVersion("key", "b", v1)
+	if err != nil || v2 <= v1 {
+		t.Fatalf("set at the current version: got %d, %v", v2, err)
+	}
+	if _, err := s.SetIfVersion("key", "c", v1); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("set at a stale version: got %v", err)
+	}
+	if val := s.Get("key"); *val != "b" {
+		t.Errorf("a stale write went through: %v", *val)
+	}
+
+	s.HSET("hash", "f", 1)
+	if _, err := s.SetIfVersion("hash", "x", s.Version("hash")); !errors.Is(err, ErrWrongType) {
+		t.Errorf("SetIfVersion on a hash: got %v", err)
+	}
+}
+
+func TestSetIfVersionRace(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+	s.Set("counter", 0)
+
+	// read-modify-write retried on conflict never loses an update
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				for {
+					val, version := s.GetWithVersion("counter")
+					if _, err := s.SetIfVersion("counter", (*val).(int)+1, version); err == nil {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if val := s.Get("counter"); *val != 400 {
+		t.Errorf("counter: got %v, want 400", *val)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	if ok, err := s.CompareAndSwap("key", nil, 1); err != nil || ok {
+		t.Errorf("CAS on a missing key: got %v, %v", ok, err)
+	}
+
+	s.Set("key", 1)
+	if ok, err := s.CompareAndSwap("key", float64(1), 2); err != nil || !ok {
+		t.Errorf("CAS with an equal number of another type: got %v, %v", ok, err)
+	}
+	if ok, _ := s.CompareAndSwap("key", 1, 3); ok {
+		t.Errorf("CAS with a stale value succeeded")
+	}
+
+	s.Set("doc", map[string]any{"n": 1, "tags": []any{"a"}})
+	ok, err := s.CompareAndSwap("doc", map[string]any{"n": float64(1), "tags": []any{"a"}}, "done")
+	if err != nil || !ok {
+		t.Errorf("CAS on a document: got %v, %v", ok, err)
+	}
+	if val := s.Get("doc"); *val != "done" {
+		t.Errorf("doc: got %v", *val)
+	}
+}
+
+func TestVersionRollback(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+	s.Set("key", "a")
+	before := s.Version("key")
+
+	s.Update(func(tx *Tx) error {
+		tx.Set("key", "b")
+		tx.Set("new", 1)
+		return errors.New("abort")
+	})
+
+	if v := s.Version("key"); v != before {
+		t.Errorf("rollback left version %d, want %d", v, before)
+	}
+	if v := s.Version("new"); v != 0 {
+		t.Errorf("rolled back key has version %d", v)
+	}
+}
@@ -0,0 +1,479 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ErrNotValidFloat is returned for a score that is NaN or cannot be parsed.
+var ErrNotValidFloat = errors.New("score is not a valid float")
+
+// ZMember is a sorted set member with its score.
+type ZMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// zset pairs a member to score index with a skiplist ordered by score.
+type zset struct {
+	dict map[string]float64
+	zsl  *skiplist
+}
+
+func newZSet() *zset {
+	return &zset{
+		dict: make(map[string]float64),
+		zsl:  newSkiplist(),
+	}
+}
+
+// ZADD adds members or updates their scores and returns how many were added.
+func (s *Storage) ZADD(key string, members ...ZMember) (int, error) {
+	if err := s.checkMemory(); err != nil {
+		return 0, err
+	}
+
+	if len(members) == 0 {
+		return 0, errors.New("WrongArgs")
+	}
+	for _, m := range members {
+		if math.IsNaN(m.Score) {
+			return 0, ErrNotValidFloat
+		}
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return 0, err
+	}
+
+	zs, ok := sh.zsets[key]
+	if !ok {
+		zs = newZSet()
+		sh.zsets[key] = zs
+	}
+
+	added, changed := 0, 0
+	for _, m := range members {
+		old, exists := zs.dict[m.Member]
+		switch {
+		case zs.set(m.Member, m.Score):
+			added++
+		case exists && old != m.Score:
+			changed++
+		}
+	}
+	// members already there with the same score are not a change
+	if added+changed > 0 {
+		sh.bumpVersion(key)
+		s.propagate(zaddArgs(key, members)...)
+	}
+
+	s.logger.Info("ZADD executed", zap.String("key", key), zap.Int("added", added))
+	return added, nil
+}
+
+// ZINCRBY adds increment to the score of member, adding it at 0 if needed,
+// and returns the new score.
+func (s *Storage) ZINCRBY(key string, increment float64, member string) (float64, error) {
+	if err := s.checkMemory(); err != nil {
+		return 0, err
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return 0, err
+	}
+
+	zs, ok := sh.zsets[key]
+	if !ok {
+		zs = newZSet()
+	}
+
+	score := zs.dict[member] + increment
+	if math.IsNaN(score) {
+		return 0, ErrNotValidFloat
+	}
+
+	zs.set(member, score)
+	sh.zsets[key] = zs
+	sh.bumpVersion(key)
+	s.propagate("ZADD", key, scoreArg(score), member)
+
+	s.logger.Info("ZINCRBY executed", zap.String("key", key), zap.String("member", member))
+	return score, nil
+}
+
+// ZSCORE returns the score of member, nil if it is not in the set.
+func (s *Storage) ZSCORE(key string, member string) (*float64, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return nil, err
+	}
+
+	zs, ok := sh.zsets[key]
+	if !ok {
+		return nil, nil
+	}
+
+	score, ok := zs.dict[member]
+	if !ok {
+		return nil, nil
+	}
+
+	return &score, nil
+}
+
+// ZCARD returns the number of members in the sorted set.
+func (s *Storage) ZCARD(key string) (int, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return 0, err
+	}
+
+	zs, ok := sh.zsets[key]
+	if !ok {
+		return 0, nil
+	}
+
+	return len(zs.dict), nil
+}
+
+// ZRANK returns the 0-based rank of member by ascending score, nil if it is not in the set.
+func (s *Storage) ZRANK(key string, member string) (*int, error) {
+	return s.zrank(key, member, false)
+}
+
+// ZREVRANK returns the 0-based rank of member by descending score, nil if it is not in the set.
+func (s *Storage) ZREVRANK(key string, member string) (*int, error) {
+	return s.zrank(key, member, true)
+}
+
+// ZRANGE returns the members between the start and stop ranks inclusive,
+// with the same negative index rules as LRANGE. rev orders by descending score.
+func (s *Storage) ZRANGE(key string, start, stop int, rev bool) ([]ZMember, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return nil, err
+	}
+
+	res := []ZMember{}
+	zs, ok := sh.zsets[key]
+	if !ok {
+		return res, nil
+	}
+
+	start, stop, ok = listRange(start, stop, zs.zsl.length)
+	if !ok {
+		return res, nil
+	}
+
+	var x *skiplistNode
+	if rev {
+		x = zs.zsl.byRank(zs.zsl.length - start)
+	} else {
+		x = zs.zsl.byRank(start + 1)
+	}
+
+	for i := start; i <= stop && x != nil; i++ {
+		res = append(res, ZMember{Member: x.member, Score: x.score})
+		if rev {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+
+	return res, nil
+}
+
+// ZRANGEBYSCORE returns the members with a score between min and max. Bounds
+// are numbers, "-inf" or "+inf", and a leading "(" makes them exclusive.
+func (s *Storage) ZRANGEBYSCORE(key string, min, max string) ([]ZMember, error) {
+	r, err := parseScoreRange(min, max)
+	if err != nil {
+		return nil, err
+	}
+
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return nil, err
+	}
+
+	res := []ZMember{}
+	zs, ok := sh.zsets[key]
+	if !ok {
+		return res, nil
+	}
+
+	for x := zs.zsl.firstInScoreRange(r); x != nil && r.belowMax(x.score); x = x.level[0].forward {
+		res = append(res, ZMember{Member: x.member, Score: x.score})
+	}
+
+	return res, nil
+}
+
+// ZRANGEBYLEX returns the members between min and max in member order, which
+// is only meaningful when all members share a score. Bounds start with "[" for
+// inclusive or "(" for exclusive, "-" and "+" stand for the infinities.
+func (s *Storage) ZRANGEBYLEX(key string, min, max string) ([]string, error) {
+	r, err := parseLexRange(min, max)
+	if err != nil {
+		return nil, err
+	}
+
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return nil, err
+	}
+
+	res := []string{}
+	zs, ok := sh.zsets[key]
+	if !ok {
+		return res, nil
+	}
+
+	for x := zs.zsl.firstInLexRange(r); x != nil && r.belowMax(x.member); x = x.level[0].forward {
+		res = append(res, x.member)
+	}
+
+	return res, nil
+}
+
+// ZREM removes members and returns how many were in the set.
+func (s *Storage) ZREM(key string, members ...string) (int, error) {
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return 0, err
+	}
+	defer sh.removeIfEmpty(key)
+
+	zs, ok := sh.zsets[key]
+	if !ok {
+		return 0, nil
+	}
+
+	removed := 0
+	for _, member := range members {
+		if zs.remove(member) {
+			removed++
+		}
+	}
+	if removed > 0 {
+		sh.bumpVersion(key)
+		s.propagate(append([]any{"ZREM", key}, stringArgs(members)...)...)
+	}
+
+	s.logger.Info("ZREM executed", zap.String("key", key), zap.Int("removed", removed))
+	return removed, nil
+}
+
+// ZREMRANGEBYRANK removes the members between the start and stop ranks
+// inclusive and returns how many were removed.
+func (s *Storage) ZREMRANGEBYRANK(key string, start, stop int) (int, error) {
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return 0, err
+	}
+	defer sh.removeIfEmpty(key)
+
+	zs, ok := sh.zsets[key]
+	if !ok {
+		return 0, nil
+	}
+
+	start, stop, ok = listRange(start, stop, zs.zsl.length)
+	if !ok {
+		return 0, nil
+	}
+
+	var doomed []string
+	for x, i := zs.zsl.byRank(start+1), start; x != nil && i <= stop; x, i = x.level[0].forward, i+1 {
+		doomed = append(doomed, x.member)
+	}
+	for _, member := range doomed {
+		zs.remove(member)
+	}
+	if len(doomed) > 0 {
+		sh.bumpVersion(key)
+		s.propagate(append([]any{"ZREM", key}, stringArgs(doomed)...)...)
+	}
+
+	s.logger.Info("ZREMRANGEBYRANK executed", zap.String("key", key), zap.Int("removed", len(doomed)))
+	return len(doomed), nil
+}
+
+// ZREMRANGEBYSCORE removes the members with a score between min and max and
+// returns how many were removed. Bounds follow ZRANGEBYSCORE.
+func (s *Storage) ZREMRANGEBYSCORE(key string, min, max string) (int, error) {
+	r, err := parseScoreRange(min, max)
+	if err != nil {
+		return 0, err
+	}
+
+	sh, unlock := s.lockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return 0, err
+	}
+	defer sh.removeIfEmpty(key)
+
+	zs, ok := sh.zsets[key]
+	if !ok {
+		return 0, nil
+	}
+
+	var doomed []string
+	for x := zs.zsl.firstInScoreRange(r); x != nil && r.belowMax(x.score); x = x.level[0].forward {
+		doomed = append(doomed, x.member)
+	}
+	for _, member := range doomed {
+		zs.remove(member)
+	}
+	if len(doomed) > 0 {
+		sh.bumpVersion(key)
+		s.propagate(append([]any{"ZREM", key}, stringArgs(doomed)...)...)
+	}
+
+	s.logger.Info("ZREMRANGEBYSCORE executed", zap.String("key", key), zap.Int("removed", len(doomed)))
+	return len(doomed), nil
+}
+
+func (s *Storage) zrank(key string, member string, rev bool) (*int, error) {
+	sh, unlock := s.rlockKey(key)
+	defer unlock()
+
+	if err := sh.checkType(key, TypeZSet); err != nil {
+		return nil, err
+	}
+
+	zs, ok := sh.zsets[key]
+	if !ok {
+		return nil, nil
+	}
+
+	score, ok := zs.dict[member]
+	if !ok {
+		return nil, nil
+	}
+
+	rank := zs.zsl.rank(score, member) - 1
+	if rev {
+		rank = zs.zsl.length - 1 - rank
+	}
+
+	return &rank, nil
+}
+
+// set adds the member or moves it to its new score and reports whether it was added.
+func (zs *zset) set(member string, score float64) bool {
+	old, ok := zs.dict[member]
+	if ok {
+		if old == score {
+			return false
+		}
+		zs.zsl.delete(old, member)
+	}
+
+	zs.dict[member] = score
+	zs.zsl.insert(score, member)
+
+	return !ok
+}
+
+func (zs *zset) remove(member string) bool {
+	score, ok := zs.dict[member]
+	if !ok {
+		return false
+	}
+
+	delete(zs.dict, member)
+	zs.zsl.delete(score, member)
+
+	return true
+}
+
+// members returns every member in ascending score order.
+func (zs *zset) members() []ZMember {
+	res := make([]ZMember, 0, zs.zsl.length)
+	for x := zs.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		res = append(res, ZMember{Member: x.member, Score: x.score})
+	}
+
+	return res
+}
+
+func parseScoreRange(min, max string) (scoreRange, error) {
+	var r scoreRange
+	var err error
+
+	if r.min, r.minEx, err = parseScoreBound(min); err != nil {
+		return r, err
+	}
+	if r.max, r.maxEx, err = parseScoreBound(max); err != nil {
+		return r, err
+	}
+
+	return r, nil
+}
+
+func parseScoreBound(bound string) (float64, bool, error) {
+	exclusive := strings.HasPrefix(bound, "(")
+	if exclusive {
+		bound = bound[1:]
+	}
+
+	score, err := strconv.ParseFloat(bound, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, false, ErrNotValidFloat
+	}
+
+	return score, exclusive, nil
+}
+
+func parseLexRange(min, max string) (lexRange, error) {
+	var r lexRange
+
+	switch {
+	case min == "-":
+		r.minInf = true
+	case strings.HasPrefix(min, "[") || strings.HasPrefix(min, "("):
+		r.min, r.minEx = min[1:], min[0] == '('
+	default:
+		return r, ErrSyntax
+	}
+
+	switch {
+	case max == "+":
+		r.maxInf = true
+	case strings.HasPrefix(max, "[") || strings.HasPrefix(max, "("):
+		r.max, r.maxEx = max[1:], max[0] == '('
+	default:
+		return r, ErrSyntax
+	}
+
+	return r, nil
+}
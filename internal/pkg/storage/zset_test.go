@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSkiplistAgainstSort(t *testing.T) {
+	zs := newZSet()
+	expected := make(map[string]float64)
+
+	for i := 0; i < 2000; i++ {
+		member := fmt.Sprintf("m%d", rand.Intn(300))
+		if rand.Intn(4) == 0 {
+			zs.remove(member)
+			delete(expected, member)
+			continue
+		}
+
+		score := float64(rand.Intn(50))
+		zs.set(member, score)
+		expected[member] = score
+	}
+
+	want := make([]ZMember, 0, len(expected))
+	for member, score := range expected {
+		want = append(want, ZMember{Member: member, Score: score})
+	}
+	sort.Slice(want, func(i, j int) bool {
+		if want[i].Score != want[j].Score {
+			return want[i].Score < want[j].Score
+		}
+		return want[i].Member < want[j].Member
+	})
+
+	if got := zs.members(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("skiplist order differs from sort")
+	}
+
+	for i, m := range want {
+		if rank := zs.zsl.rank(m.Score, m.Member); rank != i+1 {
+			t.Fatalf("rank of %s: got %d, want %d", m.Member, rank, i+1)
+		}
+		if x := zs.zsl.byRank(i + 1); x == nil || x.member != m.Member {
+			t.Fatalf("byRank(%d): got %v, want %s", i+1, x, m.Member)
+		}
+	}
+}
+
+func TestZSetCommands(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	n, _ := s.ZADD("board",
+		ZMember{"ann", 30}, ZMember{"bob", 10}, ZMember{"cid", 20}, ZMember{"dan", 20})
+	if n != 4 {
+		t.Errorf("ZADD: got %d", n)
+	}
+	if n, _ := s.ZADD("board", ZMember{"bob", 40}); n != 0 {
+		t.Errorf("ZADD update counted as added")
+	}
+
+	got, _ := s.ZRANGE("board", 0, -1, false)
+	want := []ZMember{{"cid", 20}, {"dan", 20}, {"ann", 30}, {"bob", 40}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ZRANGE: got %v", got)
+	}
+
+	got, _ = s.ZRANGE("board", 0, 1, true)
+	if !reflect.DeepEqual(got, []ZMember{{"bob", 40}, {"ann", 30}}) {
+		t.Errorf("ZRANGE rev: got %v", got)
+	}
+
+	if score, _ := s.ZINCRBY("board", 15, "cid"); score != 35 {
+		t.Errorf("ZINCRBY: got %v", score)
+	}
+	if rank, _ := s.ZRANK("board", "cid"); rank == nil || *rank != 2 {
+		t.Errorf("ZRANK: got %v", rank)
+	}
+	if rank, _ := s.ZREVRANK("board", "cid"); rank == nil || *rank != 1 {
+		t.Errorf("ZREVRANK: got %v", rank)
+	}
+	if rank, _ := s.ZRANK("board", "eve"); rank != nil {
+		t.Errorf("ZRANK of missing member: got %d", *rank)
+	}
+	if score, _ := s.ZSCORE("board", "dan"); score == nil || *score != 20 {
+		t.Errorf("ZSCORE: got %v", score)
+	}
+
+	got, _ = s.ZRANGEBYSCORE("board", "(20", "35")
+	if !reflect.DeepEqual(got, []ZMember{{"ann", 30}, {"cid", 35}}) {
+		t.Errorf("ZRANGEBYSCORE: got %v", got)
+	}
+	got, _ = s.ZRANGEBYSCORE("board", "-inf", "+inf")
+	if len(got) != 4 {
+		t.Errorf("ZRANGEBYSCORE infinite: got %v", got)
+	}
+	if _, err := s.ZRANGEBYSCORE("board", "low", "5"); !errors.Is(err, ErrNotValidFloat) {
+		t.Errorf("ZRANGEBYSCORE bad bound: got %v", err)
+	}
+
+	if n, _ := s.ZREMRANGEBYSCORE("board", "-inf", "(30"); n != 1 {
+		t.Errorf("ZREMRANGEBYSCORE: got %d", n)
+	}
+	if n, _ := s.ZREMRANGEBYRANK("board", -1, -1); n != 1 {
+		t.Errorf("ZREMRANGEBYRANK: got %d", n)
+	}
+	if n, _ := s.ZREM("board", "ann", "eve"); n != 1 {
+		t.Errorf("ZREM: got %d", n)
+	}
+	if n, _ := s.ZCARD("board"); n != 1 {
+		t.Errorf("ZCARD: got %d", n)
+	}
+
+	s.ZREM("board", "cid")
+	if s.Exists("board") != 0 {
+		t.Errorf("empty sorted set still exists")
+	}
+}
+
+func TestZADDWithoutChange(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.ZADD("board", ZMember{"ann", 30}, ZMember{"bob", 10})
+	version, changes := s.Version("board"), s.dirty.Load()
+
+	if n, err := s.ZADD("board", ZMember{"ann", 30}, ZMember{"bob", 10}); n != 0 || err != nil {
+		t.Errorf("ZADD of the same scores: got %d, %v", n, err)
+	}
+	if v := s.Version("board"); v != version {
+		t.Errorf("ZADD of the same scores changed the version from %d to %d", version, v)
+	}
+	if c := s.dirty.Load(); c != changes {
+		t.Errorf("ZADD of the same scores was logged as %d changes", c-changes)
+	}
+
+	s.ZADD("board", ZMember{"ann", 30}, ZMember{"bob", 15})
+	if v := s.Version("board"); v == version {
+		t.Errorf("ZADD of a new score kept the version")
+	}
+}
+
+func TestZRANGEBYLEX(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	for _, m := range []string{"a", "b", "c", "d", "e"} {
+		s.ZADD("names", ZMember{m, 0})
+	}
+
+	cases := []struct {
+		min, max string
+		expected []string
+	}{
+		{"-", "+", []string{"a", "b", "c", "d", "e"}},
+		{"[b", "(d", []string{"b", "c"}},
+		{"(b", "[d", []string{"c", "d"}},
+		{"[x", "+", []string{}},
+	}
+
+	for _, c := range cases {
+		got, err := s.ZRANGEBYLEX("names", c.min, c.max)
+		if err != nil || !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("ZRANGEBYLEX %s %s: got %v, %v", c.min, c.max, got, err)
+		}
+	}
+
+	if _, err := s.ZRANGEBYLEX("names", "b", "+"); err != ErrSyntax {
+		t.Errorf("ZRANGEBYLEX bad bound: got %v", err)
+	}
+}
+
+func TestZSetPersistence(t *testing.T) {
+	s, err := NewStorage()
+	if err != nil {
+		t.Fatalf("no storage: %v", err)
+	}
+
+	s.ZADD("board", ZMember{"ann", 1.5}, ZMember{"bob", -2})
+	s.Set("scalar", "value")
+
+	path := filepath.Join(t.TempDir(), "dump.json")
+	if err := s.SaveToFile(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, _ := NewStorage()
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	got, _ := loaded.ZRANGE("board", 0, -1, false)
+	if !reflect.DeepEqual(got, []ZMember{{"bob", -2}, {"ann", 1.5}}) {
+		t.Errorf("loaded sorted set: got %v", got)
+	}
+	if loaded.Type("board") != TypeZSet {
+		t.Errorf("loaded sorted set has type %s", loaded.Type("board"))
+	}
+}